@@ -0,0 +1,138 @@
+package simcache
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAuditConcurrency is the most calls to an audit's check function that run at once, unless AuditOptions
+	// sets another limit.
+	defaultAuditConcurrency = 8
+	// defaultAuditKeyLimit is the most keys an AuditReport lists for each outcome, unless AuditOptions sets another
+	// limit.
+	defaultAuditKeyLimit = 100
+)
+
+// AuditOptions configures a call to Audit. The zero value only reports the items check rejects, runs up to 8 checks at
+// once and lists up to 100 keys for each outcome.
+type AuditOptions struct {
+	// Repair deletes the items check rejects, unless they were written again while being checked.
+	Repair bool
+	// Concurrency is the most calls to check that run at once. 0 or less uses the default of 8.
+	Concurrency int
+	// KeyLimit is the most keys the report lists for each outcome. 0 or less uses the default of 100.
+	KeyLimit int
+}
+
+// AuditReport counts the outcomes of an Audit.
+type AuditReport struct {
+	Checked int
+	Valid   int
+	Invalid int
+	Errored int
+	// InvalidKeys and ErroredKeys list the keys that were invalid or could not be checked, up to AuditOptions.KeyLimit
+	// of each.
+	InvalidKeys []string
+	ErroredKeys []string
+}
+
+// auditEntry is a live item sampled by Audit, with when it was written so that it is only deleted if unchanged.
+type auditEntry[T any] struct {
+	key     string
+	value   T
+	written time.Time
+}
+
+// Audit checks a random sample of the live items in the cache against a source of truth, calling check for each
+// with up to opts.Concurrency calls running at once. sampleFraction is the fraction of the items to check, with 1
+// checking them all. When opts.Repair is set, items that check rejects are deleted, unless they were written again
+// while being checked; otherwise they are only reported. If ctx is done before every item was checked, it returns
+// the report so far along with ctx.Err().
+func (c *cache[T]) Audit(ctx context.Context, check func(ctx context.Context, key string, value T) (bool, error), sampleFraction float64, opts AuditOptions) (AuditReport, error) {
+	concurrency, keyLimit := opts.Concurrency, opts.KeyLimit
+	if concurrency <= 0 {
+		concurrency = defaultAuditConcurrency
+	}
+	if keyLimit <= 0 {
+		keyLimit = defaultAuditKeyLimit
+	}
+
+	c.rlock()
+	var sample []auditEntry[T]
+	for k, i := range c.items {
+		if !c.expired(i) && rand.Float64() < sampleFraction {
+			sample = append(sample, auditEntry[T]{key: k, value: i.value, written: i.written})
+		}
+	}
+	c.mutex.RUnlock()
+
+	var report AuditReport
+	var mutex sync.Mutex
+	entries := make(chan auditEntry[T])
+	var wg sync.WaitGroup
+	for n := 0; n < min(concurrency, len(sample)); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range entries {
+				ok, err := check(ctx, e.key, e.value)
+				if ok && err == nil {
+					mutex.Lock()
+					report.Checked++
+					report.Valid++
+					mutex.Unlock()
+					continue
+				}
+				if err == nil && opts.Repair {
+					c.deleteIfWritten(e.key, e.written)
+				}
+				mutex.Lock()
+				report.Checked++
+				if err != nil {
+					report.Errored++
+					if len(report.ErroredKeys) < keyLimit {
+						report.ErroredKeys = append(report.ErroredKeys, e.key)
+					}
+				} else {
+					report.Invalid++
+					if len(report.InvalidKeys) < keyLimit {
+						report.InvalidKeys = append(report.InvalidKeys, e.key)
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	var err error
+send:
+	for _, e := range sample {
+		select {
+		case entries <- e:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break send
+		}
+	}
+	close(entries)
+	wg.Wait()
+	return report, err
+}
+
+// deleteIfWritten deletes the item for a given key if it was last written at the given time.
+func (c *cache[T]) deleteIfWritten(key string, written time.Time) {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	if i, found := c.items[key]; !found || !i.written.Equal(written) {
+		return
+	}
+	delete(c.items, key)
+	c.length.Store(int64(len(c.items)))
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+}
@@ -0,0 +1,141 @@
+package simcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_Audit(t *testing.T) {
+	type unitTest struct {
+		name     string
+		opts     AuditOptions
+		fraction float64
+		expected AuditReport
+		length   int
+	}
+
+	tests := []unitTest{
+		{
+			name:     "Report Only",
+			fraction: 1,
+			expected: AuditReport{Checked: 210, Valid: 50, Invalid: 150, Errored: 10},
+			length:   210,
+		},
+		{
+			name:     "Repair",
+			opts:     AuditOptions{Repair: true},
+			fraction: 1,
+			expected: AuditReport{Checked: 210, Valid: 50, Invalid: 150, Errored: 10},
+			length:   60,
+		},
+		{
+			name:     "No Sample",
+			opts:     AuditOptions{Repair: true},
+			fraction: 0,
+			expected: AuditReport{},
+			length:   210,
+		},
+		{
+			name:     "Limits",
+			opts:     AuditOptions{Concurrency: 2, KeyLimit: 10},
+			fraction: 1,
+			expected: AuditReport{Checked: 210, Valid: 50, Invalid: 150, Errored: 10},
+			length:   210,
+		},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		concurrency, keyLimit := defaultAuditConcurrency, defaultAuditKeyLimit
+		if test.opts.Concurrency > 0 {
+			concurrency = test.opts.Concurrency
+		}
+		if test.opts.KeyLimit > 0 {
+			keyLimit = test.opts.KeyLimit
+		}
+		for i := 0; i < 50; i++ {
+			c.Set("good:"+strconv.Itoa(i), i)
+		}
+		for i := 0; i < 150; i++ {
+			c.Set("bad:"+strconv.Itoa(i), i)
+		}
+		for i := 0; i < 10; i++ {
+			c.Set("error:"+strconv.Itoa(i), i)
+		}
+		c.Set("expired", 0, time.Nanosecond)
+		time.Sleep(time.Nanosecond * 2)
+
+		var running, maxRunning atomic.Int32
+		check := func(ctx context.Context, key string, value int) (bool, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				current := maxRunning.Load()
+				if n <= current || maxRunning.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			time.Sleep(time.Microsecond * 100)
+			if strings.HasPrefix(key, "error:") {
+				return false, errors.New("database unavailable")
+			}
+			return !strings.HasPrefix(key, "bad:"), nil
+		}
+
+		report, err := c.Audit(context.Background(), check, test.fraction, test.opts)
+		if err != nil {
+			t.Fatalf("%s FAILED - unexpected error %v", test.name, err)
+		}
+		if report.Checked != test.expected.Checked || report.Valid != test.expected.Valid ||
+			report.Invalid != test.expected.Invalid || report.Errored != test.expected.Errored {
+			t.Fatalf("%s FAILED - expected %+v but got %+v", test.name, test.expected, report)
+		}
+		if len(report.InvalidKeys) != min(report.Invalid, keyLimit) || len(report.ErroredKeys) != min(report.Errored, keyLimit) {
+			t.Fatalf("%s FAILED - expected %d and %d keys but got %d and %d", test.name, min(report.Invalid, keyLimit),
+				min(report.Errored, keyLimit), len(report.InvalidKeys), len(report.ErroredKeys))
+		}
+		for _, k := range report.InvalidKeys {
+			if !strings.HasPrefix(k, "bad:") {
+				t.Fatalf("%s FAILED - %q was reported invalid", test.name, k)
+			}
+		}
+		if length := c.Len(); length != test.length+1 {
+			t.Fatalf("%s FAILED - expected %d items but got %d", test.name, test.length+1, length)
+		}
+		if n := maxRunning.Load(); n > int32(concurrency) {
+			t.Fatalf("%s FAILED - expected at most %d concurrent checks but got %d", test.name, concurrency, n)
+		}
+	}
+}
+
+func TestCache_AuditCancelled(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var checked atomic.Int32
+	check := func(ctx context.Context, key string, value int) (bool, error) {
+		if checked.Add(1) == 10 {
+			cancel()
+		}
+		return false, nil
+	}
+
+	report, err := c.Audit(ctx, check, 1, AuditOptions{Repair: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FAILED - expected %v but got %v", context.Canceled, err)
+	}
+	if report.Checked >= 100 || report.Checked != report.Invalid {
+		t.Fatalf("FAILED - unexpected report %+v", report)
+	}
+	if length := c.Len(); length != 100-report.Invalid {
+		t.Fatalf("FAILED - expected %d items but got %d", 100-report.Invalid, length)
+	}
+}
@@ -1,97 +1,452 @@
 package simcache
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unique"
 )
 
+// ErrTooManyEntries is returned by the checked aggregate accessors when the cache holds more live items than the
+// limit set with WithSnapshotLimit.
+var ErrTooManyEntries = errors.New("simcache: too many entries")
+
+// ErrNilValue is returned by TrySet when the cache was created WithRejectNilValues and the value is nil.
+var ErrNilValue = errors.New("simcache: nil value")
+
+// ErrTTLOutOfRange is returned by TrySet when the cache was created WithStrictTTL and the TTL is outside of the
+// bounds set by WithMinTTL and WithMaxTTL.
+var ErrTTLOutOfRange = errors.New("simcache: ttl out of range")
+
+// ErrKeyExists is returned by TrySet when the cache was created WithNoOverwrite and a live item is already stored for
+// the key.
+var ErrKeyExists = errors.New("simcache: key exists")
+
+// ErrPurgeInProgress is returned by TryPurge when another purge is already running.
+var ErrPurgeInProgress = errors.New("simcache: purge in progress")
+
 // Cache holds any items of type T that are cleared after a given TTL.
 // The cache clears any expired items upon any retrieval operation.
 type Cache[T any] struct {
 	*cache[T]
 }
 
+// Entry is a key-value pair held by a Cache, along with when it expires.
+type Entry[T any] struct {
+	Key        string
+	Value      T
+	Expiration time.Time
+}
+
 // New creates an empty Cache where the TTL for item's added will be set to the given duration.
-func New[T any](defaultTTL time.Duration) *Cache[T] {
+// Optional behaviour can be enabled by passing one or more Option values.
+// It panics if the options set a minimum TTL greater than the maximum TTL.
+func New[T any](defaultTTL time.Duration, opts ...Option[T]) *Cache[T] {
 	items := make(map[string]item[T])
-	return &Cache[T]{cache: &cache[T]{
+	c := &cache[T]{
 		items:      items,
 		defaultTTL: defaultTTL,
-		mutex:      &sync.RWMutex{},
-	}}
+	}
+	c.stats.waits = newHistogram(lockWaitBuckets)
+	c.stats.loads = newHistogram(defaultLoadBuckets)
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.minTTL > 0 && c.maxTTL > 0 && c.minTTL > c.maxTTL {
+		panic("simcache: WithMinTTL is greater than WithMaxTTL")
+	}
+	wrapper := &Cache[T]{cache: c}
+	startJanitor(wrapper)
+	return wrapper
 }
 
 // Add inserts the item T into the cache for a given key if no item has been already added with the same key.
 // It returns false if the item was not added due to an existing item with the same key being there.
-// It returns true if the item was added successfully.
+// It returns true if the item was added successfully. Expired items, including items written before the current
+// generation and items left in the cache by WithReadOnlyGets, are treated as absent and replaced.
+// When the cache was created WithRejectNilValues, nil values are not added and false is returned.
 func (c *cache[T]) Add(key string, value T, ttl ...time.Duration) bool {
-	expiration := calculateExpiration(c.defaultTTL, ttl...)
-	c.mutex.RLock()
-	_, found := c.items[key]
-	if found {
-		c.mutex.RUnlock()
+	if c.rejects(value) {
 		return false
 	}
-
-	c.mutex.RUnlock()
-	c.mutex.Lock()
+	key = c.intern(key)
+	i, clamped := c.newItem(value, ttl...)
+	i.origin = c.caller(1)
+	c.lock()
 	defer c.mutex.Unlock()
-	c.items[key] = item[T]{
-		value:      value,
-		expiration: expiration,
+	if old, found := c.items[key]; found && !c.expired(old) {
+		return false
 	}
+	c.store(key, i, clamped)
 	return true
 }
 
+// GetOrSet returns the value in the cache for a given key and true if it was found. Otherwise, it adds the value to
+// the cache like Add and returns it and false. The lookup and the insert are done under one lock, so of several
+// goroutines calling it for the same key only one adds its value and the others get that value back.
+// Expired items are treated as absent and replaced. Values rejected by the cache's options are returned but not added.
+func (c *cache[T]) GetOrSet(key string, value T, ttl ...time.Duration) (T, bool) {
+	if c.rejects(value) {
+		return value, false
+	}
+	key = c.intern(key)
+	i, clamped := c.newItem(value, ttl...)
+	i.origin = c.caller(1)
+	c.lock()
+	defer c.mutex.Unlock()
+	if old, found := c.items[key]; found && !c.expired(old) {
+		return old.value, true
+	}
+	c.store(key, i, clamped)
+	return value, false
+}
+
 // Set replaces the value in the cache for a given key. If no such key exists, it adds it to the cache.
 // If no duration, or a value of 0, is specified it uses the default TTL when the cache was made.
 // Only the first duration given is used when multiple are passed in.
+// Values rejected by the cache's options are silently ignored; use TrySet to find out when that happens.
+// A TTL outside of the bounds set by WithMinTTL and WithMaxTTL is clamped to them.
+// When the cache was created WithNoOverwrite, Set panics if a live item is already stored for the key.
 func (c *cache[T]) Set(key string, value T, ttl ...time.Duration) {
-	expiration := calculateExpiration(c.defaultTTL, ttl...)
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.items[key] = item[T]{
-		value:      value,
-		expiration: expiration,
+	if err := c.set(key, value, false, 0, ttl...); errors.Is(err, ErrKeyExists) {
+		panicOverwrite(key)
+	}
+}
+
+// panicOverwrite panics for a Set that would overwrite a live item in a cache created WithNoOverwrite.
+func panicOverwrite(key string) {
+	panic("simcache: Set would overwrite key " + strconv.Quote(key) + " in a cache created WithNoOverwrite")
+}
+
+// TrySet replaces the value in the cache for a given key like Set, but returns an error if the value was rejected.
+// It returns ErrNilValue when the cache was created WithRejectNilValues and the value is nil, and ErrTTLOutOfRange
+// when the cache was created WithStrictTTL and the TTL is outside of the bounds set by WithMinTTL and WithMaxTTL.
+// It returns ErrKeyExists when the cache was created WithNoOverwrite and a live item is already stored for the key.
+func (c *cache[T]) TrySet(key string, value T, ttl ...time.Duration) error {
+	return c.set(key, value, c.strictTTL, 0, ttl...)
+}
+
+// set stores the value for a given key, rejecting an out of bounds TTL instead of clamping it if strict is true.
+// skip is the number of frames between the caller of set and the code that made the write, for WithOriginTracking.
+func (c *cache[T]) set(key string, value T, strict bool, skip int, ttl ...time.Duration) error {
+	if c.rejects(value) {
+		return ErrNilValue
+	}
+	key = c.intern(key)
+	i, clamped := c.newItem(value, ttl...)
+	i.origin = c.caller(2 + skip)
+	if clamped && strict {
+		return ErrTTLOutOfRange
+	}
+	c.lock()
+	if c.noOverwrite {
+		if old, found := c.items[key]; found && !c.expired(old) {
+			c.mutex.Unlock()
+			return ErrKeyExists
+		}
+	}
+	old, replaced := c.store(key, i, clamped)
+	onReplace := c.onReplace
+	c.mutex.Unlock()
+	if replaced && onReplace != nil {
+		onReplace(key, old, value)
+	}
+	return nil
+}
+
+// SetIfStillPresent replaces the value in the cache for a given key like Set, but only if the key is still in the
+// cache and unexpired. It returns whether the value was written.
+// Use it to write back values read from Items or Values, so that items which expired or were deleted since are not
+// brought back.
+func (c *cache[T]) SetIfStillPresent(key string, value T, ttl ...time.Duration) bool {
+	if c.rejects(value) {
+		return false
+	}
+	key = c.intern(key)
+	i, clamped := c.newItem(value, ttl...)
+	i.origin = c.caller(1)
+	c.lock()
+	if old, found := c.items[key]; !found || c.expired(old) {
+		c.mutex.Unlock()
+		return false
+	}
+	old, replaced := c.store(key, i, clamped)
+	onReplace := c.onReplace
+	c.mutex.Unlock()
+	if replaced && onReplace != nil {
+		onReplace(key, old, value)
+	}
+	return true
+}
+
+// store writes the item to the cache for a given key, stamping it with the current generation. The caller must hold
+// the write lock, so that an item stored after NextGeneration is never stamped with the generation before it.
+// It returns the old value and true if the item replaced a live item, even one holding an equal value unless the cache
+// was created WithChangeDetection.
+func (c *cache[T]) store(key string, i item[T], clamped bool) (T, bool) {
+	c.stats.recordWrite(clamped)
+	i.generation = c.generation.Load()
+	old, found := c.items[key]
+	if found && c.unchanged(old, i) {
+		// Only the expiration is refreshed, so this does not count as a change and neither the version nor
+		// LastMutation moves. The snapshot is still cleared if the item now expires earlier than it records.
+		expiration, _ := c.clampExpiration(i.created, old.created, i.expiration)
+		if expiration.Before(old.expiration) {
+			c.snapshot.Store(nil)
+		}
+		old.expiration = expiration
+		old.written = i.written
+		c.items[key] = old
+		c.refreshes++
+		return *new(T), false
+	}
+	replaced := found && !c.expired(old)
+	c.items[key] = i
+	c.length.Store(int64(len(c.items)))
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	if f := c.filter.Load(); f != nil {
+		f.add(key)
 	}
+	return old.value, replaced
+}
+
+// unchanged returns true if the cache was created WithChangeDetection and the new item holds the same value as the
+// old, live item.
+func (c *cache[T]) unchanged(old, i item[T]) bool {
+	return c.equal != nil && !c.expired(old) && c.equal(old.value, i.value)
 }
 
 // Get returns the value in the cache for a given key and if it was found. If no such key exists, the returned bool will be false.
+// When the cache was created WithOpportunisticCleanup, it also deletes expired items among a random sample of others.
+// When the cache was created WithMissFilter, keys that were never added are not found without taking the lock.
+// When the cache was created WithReadOnlyGets, expired items are not deleted.
 func (c *cache[T]) Get(key string) (T, bool) {
-	c.mutex.RLock()
+	i, found := c.get(key)
+	return i.value, found
+}
+
+// GetWithExpiration returns the value in the cache for a given key like Get, along with the time it expires at in UTC.
+// Expired items are treated exactly as Get treats them. If the item is not found, the returned time is the zero time.
+func (c *cache[T]) GetWithExpiration(key string) (T, time.Time, bool) {
+	i, found := c.get(key)
+	return i.value, i.expiration, found
+}
+
+// get returns the live item for a given key and if it was found, deleting it if it has expired. If it was not found,
+// the returned item is the zero item.
+func (c *cache[T]) get(key string) (item[T], bool) {
+	if !c.mayContain(key) {
+		return item[T]{}, false
+	}
+	if !c.readOnlyGets {
+		c.cleanup()
+	}
+	c.rlock()
 	i, found := c.items[key]
 	if !found {
 		c.mutex.RUnlock()
-		return i.value, false
+		return item[T]{}, false
 	}
 
-	if i.expired() {
+	if c.expired(i) {
 		c.mutex.RUnlock()
-		c.Delete(key)
-		return i.value, false
+		if !c.readOnlyGets && c.removable(i) {
+			c.lock()
+			c.deleteRemovable([]string{key})
+			c.mutex.Unlock()
+		}
+		return item[T]{}, false
 	}
 	c.mutex.RUnlock()
+	return i, true
+}
+
+// GetFresh returns the value in the cache for a given key like Get, but only if it was written no longer than maxAge ago.
+// Items that are too old are not found but are left in the cache for other readers.
+func (c *cache[T]) GetFresh(key string, maxAge time.Duration) (T, bool) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	i, found := c.items[key]
+	if !found || c.expired(i) || time.Since(i.created) > maxAge {
+		return *new(T), false
+	}
 	return i.value, true
 }
 
+// GetStale returns the value in the cache for a given key like Get, but when the cache was created WithReadGrace it
+// also returns items that expired no longer than the grace period ago. The second bool reports whether the item has
+// expired, so the caller can refresh it.
+func (c *cache[T]) GetStale(key string) (T, bool, bool) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	i, found := c.items[key]
+	if !found || c.removable(i) {
+		return *new(T), false, false
+	}
+	return i.value, true, c.expired(i)
+}
+
+// GetAndTouch returns the value in the cache for a given key and if it was found, resetting its expiration to the
+// given TTL, or the default TTL if none is given, in the same operation. Expired items are deleted and not found.
+func (c *cache[T]) GetAndTouch(key string, ttl ...time.Duration) (T, bool) {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	i, found := c.items[key]
+	if !found {
+		return i.value, false
+	}
+	if c.expired(i) {
+		if !c.removable(i) {
+			return *new(T), false
+		}
+		delete(c.items, key)
+		c.length.Store(int64(len(c.items)))
+		c.changed()
+		c.snapshot.Store(nil)
+		c.markDirty(key)
+		return *new(T), false
+	}
+
+	now := time.Now().UTC()
+	var clamped bool
+	i.expiration, clamped = c.clampExpiration(now, i.created, calculateExpiration(now, c.defaultTTL, ttl...))
+	i.written = now
+	c.items[key] = i
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	c.stats.recordWrite(clamped)
+	return i.value, true
+}
+
+// GetAndReset returns the value in the cache for a given key and if it was found, setting the stored value to the zero
+// value of T in the same operation. The item keeps its expiration. Use it to read and reset a counter without losing
+// increments made in between.
+func (c *cache[T]) GetAndReset(key string) (T, bool) {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	i, found := c.items[key]
+	if !found || c.expired(i) {
+		return *new(T), false
+	}
+	value := i.value
+	i.value = *new(T)
+	i.written = time.Now().UTC()
+	c.items[key] = i
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	return value, true
+}
+
 // Delete removes the item from the cache for the given key.
 func (c *cache[T]) Delete(key string) {
-	c.mutex.Lock()
+	c.lock()
 	defer c.mutex.Unlock()
+	if _, found := c.items[key]; !found {
+		return
+	}
+	delete(c.items, key)
+	c.length.Store(int64(len(c.items)))
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+}
+
+// DeleteAndReturn removes the item from the cache for the given key like Delete, and returns its value and whether it
+// was found. An expired item is removed but not found.
+func (c *cache[T]) DeleteAndReturn(key string) (T, bool) {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	i, found := c.items[key]
+	if !found {
+		return *new(T), false
+	}
 	delete(c.items, key)
+	c.length.Store(int64(len(c.items)))
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	if c.expired(i) {
+		return *new(T), false
+	}
+	return i.value, true
+}
+
+// DeleteAllIfPresent removes all the given keys if every one of them holds a live item, and returns true.
+// If any key is missing or expired, nothing is removed and it returns false.
+func (c *cache[T]) DeleteAllIfPresent(keys []string) bool {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	for _, k := range keys {
+		i, found := c.items[k]
+		if !found || c.expired(i) {
+			return false
+		}
+	}
+	for _, k := range keys {
+		delete(c.items, k)
+		c.changed()
+		c.markDirty(k)
+	}
+	c.length.Store(int64(len(c.items)))
+	if len(keys) > 0 {
+		c.snapshot.Store(nil)
+	}
+	return true
 }
 
 // Items returns a copy of the cache's map that holds type T.
+// When the cache was created WithItemsSnapshot, the returned map is shared between callers and must not be modified.
 func (c *cache[T]) Items() map[string]T {
-	c.mutex.RLock()
+	if c.snapshots {
+		return c.snapshotItems()
+	}
+
+	c.rlock()
+	var removable []string
+	items := make(map[string]T, len(c.items))
+	for k, i := range c.items {
+		if c.expired(i) {
+			if c.removable(i) {
+				removable = append(removable, k)
+			}
+			continue
+		}
+		items[k] = i.value
+	}
+	c.mutex.RUnlock()
+
+	if len(removable) > 0 {
+		c.lock()
+		c.deleteRemovable(removable)
+		c.mutex.Unlock()
+	}
+	return items
+}
+
+// ItemsIncludingStale returns a copy of the cache's map like Items, but also includes items that expired no longer
+// than grace ago. Items invalidated by NextGeneration are never included. Stale items are left in the cache.
+func (c *cache[T]) ItemsIncludingStale(grace time.Duration) map[string]T {
+	c.rlock()
 	defer c.mutex.RUnlock()
 
+	cutoff := time.Now().UTC().Add(-grace)
 	items := make(map[string]T, len(c.items))
 	for k, i := range c.items {
-		if i.expired() {
-			c.mutex.RUnlock()
-			c.Delete(k)
-			c.mutex.RLock()
+		if c.outdated(i) || i.expiration.Before(cutoff) {
 			continue
 		}
 		items[k] = i.value
@@ -99,9 +454,23 @@ func (c *cache[T]) Items() map[string]T {
 	return items
 }
 
+// Len returns the number of items in the cache, including expired items that have not been deleted yet.
+func (c *cache[T]) Len() int {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	return len(c.items)
+}
+
+// ApproxLen returns the number of items in the cache like Len, but without taking the lock. It is updated as soon as
+// each write finishes, so it can briefly disagree with Len while writes are in progress.
+func (c *cache[T]) ApproxLen() int64 {
+	return c.length.Load()
+}
+
 // Keys returns a slice of the cache's keys.
 func (c *cache[T]) Keys() []string {
-	c.mutex.RLock()
+	c.rlock()
 	defer c.mutex.RUnlock()
 
 	var keys []string
@@ -113,59 +482,681 @@ func (c *cache[T]) Keys() []string {
 
 // Values returns a slice of the cache's values of type T.
 func (c *cache[T]) Values() []T {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-
+	c.rlock()
+	var removable []string
 	var values []T
 	for k, i := range c.items {
-		if i.expired() {
-			c.mutex.RUnlock()
-			c.Delete(k)
-			c.mutex.RLock()
+		if c.expired(i) {
+			if c.removable(i) {
+				removable = append(removable, k)
+			}
 			continue
 		}
 		values = append(values, i.value)
 	}
+	c.mutex.RUnlock()
+
+	if len(removable) > 0 {
+		c.lock()
+		c.deleteRemovable(removable)
+		c.mutex.Unlock()
+	}
 	return values
 }
 
-// Purge removes all expired items from the cache.
-func (c *Cache[T]) Purge() int {
-	c.mutex.RLock()
+// Collect returns the live values in the cache for the given keys, along with the keys that are missing or expired,
+// in the order they were given. Use it to poll for results stored under known keys until none are missing.
+func (c *cache[T]) Collect(keys []string) (map[string]T, []string) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	found := make(map[string]T, len(keys))
+	var missing []string
+	for _, k := range keys {
+		i, ok := c.items[k]
+		if !ok || c.expired(i) {
+			missing = append(missing, k)
+			continue
+		}
+		found[k] = i.value
+	}
+	return found, missing
+}
+
+// TTLMany returns the remaining TTL of each of the given keys that is in the cache and unexpired.
+// All durations are measured from the same instant, so they are consistent with each other.
+func (c *cache[T]) TTLMany(keys ...string) map[string]time.Duration {
+	c.rlock()
 	defer c.mutex.RUnlock()
 
+	now := time.Now().UTC()
+	ttls := make(map[string]time.Duration, len(keys))
+	for _, k := range keys {
+		i, found := c.items[k]
+		if !found || c.outdated(i) || !now.Before(i.expiration) {
+			continue
+		}
+		ttls[k] = i.expiration.Sub(now)
+	}
+	return ttls
+}
+
+// TTLAll returns the remaining TTL of every unexpired item in the cache.
+// All durations are measured from the same instant, so they are consistent with each other.
+func (c *cache[T]) TTLAll() map[string]time.Duration {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	now := time.Now().UTC()
+	ttls := make(map[string]time.Duration, len(c.items))
+	for k, i := range c.items {
+		if c.outdated(i) || !now.Before(i.expiration) {
+			continue
+		}
+		ttls[k] = i.expiration.Sub(now)
+	}
+	return ttls
+}
+
+// ItemsChecked returns a copy of the cache's map that holds type T, like Items.
+// It returns ErrTooManyEntries instead when the cache holds more live items than the limit set with WithSnapshotLimit.
+func (c *cache[T]) ItemsChecked() (map[string]T, error) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	count, err := c.liveCount()
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[string]T, count)
+	for k, i := range c.items {
+		if !c.expired(i) {
+			items[k] = i.value
+		}
+	}
+	return items, nil
+}
+
+// KeysChecked returns a slice of the cache's live keys.
+// It returns ErrTooManyEntries instead when the cache holds more live items than the limit set with WithSnapshotLimit.
+func (c *cache[T]) KeysChecked() ([]string, error) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	count, err := c.liveCount()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, count)
+	for k, i := range c.items {
+		if !c.expired(i) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// ValuesChecked returns a slice of the cache's live values of type T.
+// It returns ErrTooManyEntries instead when the cache holds more live items than the limit set with WithSnapshotLimit.
+func (c *cache[T]) ValuesChecked() ([]T, error) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	count, err := c.liveCount()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]T, 0, count)
+	for _, i := range c.items {
+		if !c.expired(i) {
+			values = append(values, i.value)
+		}
+	}
+	return values, nil
+}
+
+// liveCount returns the number of unexpired items, stopping early with ErrTooManyEntries once the count
+// exceeds the snapshot limit. The caller must hold the lock.
+func (c *cache[T]) liveCount() (int, error) {
+	count := 0
+	for _, i := range c.items {
+		if c.expired(i) {
+			continue
+		}
+		count++
+		if c.snapshotLimit > 0 && count > c.snapshotLimit {
+			return 0, ErrTooManyEntries
+		}
+	}
+	return count, nil
+}
+
+// GroupBy partitions the live items in the cache into groups named by keyFn.
+func (c *cache[T]) GroupBy(keyFn func(key string, value T) string) map[string][]Entry[T] {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	groups := make(map[string][]Entry[T])
+	for k, i := range c.items {
+		if c.expired(i) {
+			continue
+		}
+		group := keyFn(k, i.value)
+		groups[group] = append(groups[group], Entry[T]{Key: k, Value: i.value, Expiration: i.expiration})
+	}
+	return groups
+}
+
+// ReplaceFunc sets the value of every live item for which pred returns true to newValue, keeping its expiration.
+// It returns the number of items changed. If newValue is rejected by the cache's options, nothing is changed and 0 is
+// returned.
+func (c *cache[T]) ReplaceFunc(pred func(key string, value T) bool, newValue T) int {
+	if c.rejects(newValue) {
+		return 0
+	}
+	c.lock()
+	now := time.Now().UTC()
+	var replaced []Entry[T]
+	for k, i := range c.items {
+		if c.expired(i) || !pred(k, i.value) {
+			continue
+		}
+		replaced = append(replaced, Entry[T]{Key: k, Value: i.value})
+		i.value = newValue
+		i.written = now
+		c.items[k] = i
+		c.changed()
+		c.markDirty(k)
+	}
+	if len(replaced) > 0 {
+		c.snapshot.Store(nil)
+	}
+	onReplace := c.onReplace
+	c.mutex.Unlock()
+
+	if onReplace != nil {
+		for _, e := range replaced {
+			onReplace(e.Key, e.Value, newValue)
+		}
+	}
+	return len(replaced)
+}
+
+// ReplaceAll replaces the whole contents of the cache with entries, all written with the given TTL, or the default TTL
+// if none is given, and returns the live items it replaced. The new contents are built before the write lock is taken
+// and swapped in at once, so other operations see either all of the old items or all of the new ones.
+// Values rejected by the cache's options are left out. The function set with OnReplace is called for every live item
+// overwritten by an entry for the same key, after the lock is released.
+func (c *cache[T]) ReplaceAll(entries map[string]T, ttl ...time.Duration) map[string]T {
+	items := make(map[string]item[T], len(entries))
+	generation := c.generation.Load()
+	for k, v := range entries {
+		if c.rejects(v) {
+			continue
+		}
+		i, clamped := c.newItem(v, ttl...)
+		i.generation = generation
+		i.origin = c.caller(1)
+		items[c.intern(k)] = i
+		c.stats.recordWrite(clamped)
+	}
+	var filter *bloomFilter
+	if c.filter.Load() != nil {
+		filter = newBloomFilter(max(c.filterItems, len(items)), c.filterRate)
+		for k := range items {
+			filter.add(k)
+		}
+	}
+
+	c.lock()
+	if current := c.generation.Load(); current != generation {
+		// NextGeneration was called while the items were built, so they must be stamped again.
+		for k, i := range items {
+			i.generation = current
+			items[k] = i
+		}
+	}
+	old := c.items
+	c.items = items
+	c.length.Store(int64(len(items)))
+	c.changed()
+	c.snapshot.Store(nil)
+	if filter != nil {
+		c.filter.Store(filter)
+	}
+	if c.dirty != nil {
+		for k := range old {
+			c.markDirty(k)
+		}
+		for k := range items {
+			c.markDirty(k)
+		}
+	}
+	onReplace := c.onReplace
+	c.mutex.Unlock()
+
+	// The old map is no longer reachable from the cache, so it can be read without the lock.
+	replaced := make(map[string]T, len(old))
+	for k, i := range old {
+		if c.expired(i) {
+			continue
+		}
+		replaced[k] = i.value
+		if v, found := entries[k]; found && onReplace != nil && !c.rejects(v) && (c.equal == nil || !c.equal(i.value, v)) {
+			onReplace(k, i.value, v)
+		}
+	}
+	return replaced
+}
+
+// ExpireAll marks every live item in the cache as expired without removing it, and returns how many were marked.
+// Unlike deleting them, the items stay in the cache until they are next read or purged.
+func (c *cache[T]) ExpireAll() int {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now().UTC()
 	count := 0
 	for k, i := range c.items {
-		if i.expired() {
-			c.mutex.RUnlock()
-			c.Delete(k)
-			c.mutex.RLock()
-			count++
+		if c.expired(i) {
+			continue
 		}
+		i.expiration = now
+		c.items[k] = i
+		c.changed()
+		c.markDirty(k)
+		count++
+	}
+	if count > 0 {
+		c.snapshot.Store(nil)
 	}
 	return count
 }
 
+// ApplyTTL resets the expiration of every live item for which policy returns true to the TTL it returns, measured from
+// now, and returns how many items were changed. A TTL of 0 uses the default TTL, and TTLs are clamped as they are by Set.
+// It runs under a single write lock, so policy must not use the cache.
+func (c *cache[T]) ApplyTTL(policy func(key string) (time.Duration, bool)) int {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now().UTC()
+	count := 0
+	for k, i := range c.items {
+		if c.expired(i) {
+			continue
+		}
+		ttl, ok := policy(k)
+		if !ok {
+			continue
+		}
+		var clamped bool
+		i.expiration, clamped = c.clampExpiration(now, i.created, calculateExpiration(now, c.defaultTTL, ttl))
+		i.written = now
+		c.items[k] = i
+		c.changed()
+		c.markDirty(k)
+		c.stats.recordWrite(clamped)
+		count++
+	}
+	if count > 0 {
+		c.snapshot.Store(nil)
+	}
+	return count
+}
+
+// Purge removes all expired items from the cache.
+// If another purge is already running, including PurgeAtomic, PurgeCtx or the janitor, it waits for that one to finish
+// and returns its count instead of scanning the cache again.
+func (c *Cache[T]) Purge() int {
+	f, started := c.beginPurge()
+	if !started {
+		f.wg.Wait()
+		return f.value
+	}
+	defer c.endPurge(f)
+	f.value = c.sweep()
+	return f.value
+}
+
+// TryPurge removes all expired items from the cache like Purge, but returns ErrPurgeInProgress instead of waiting
+// when another purge is already running.
+func (c *Cache[T]) TryPurge() (int, error) {
+	f, started := c.beginPurge()
+	if !started {
+		return 0, ErrPurgeInProgress
+	}
+	defer c.endPurge(f)
+	f.value = c.sweep()
+	return f.value, nil
+}
+
+// beginPurge returns the purge that is already running and false, or starts a new one and returns it and true.
+// A started purge must be finished with endPurge.
+func (c *cache[T]) beginPurge() (*flight[int], bool) {
+	c.flightMutex.Lock()
+	defer c.flightMutex.Unlock()
+
+	if c.purging != nil {
+		return c.purging, false
+	}
+	c.purging = &flight[int]{}
+	c.purging.wg.Add(1)
+	return c.purging, true
+}
+
+// endPurge finishes a purge started by beginPurge, releasing any callers waiting on it.
+func (c *cache[T]) endPurge(f *flight[int]) {
+	c.flightMutex.Lock()
+	c.purging = nil
+	c.flightMutex.Unlock()
+	f.wg.Done()
+}
+
+// sweep deletes all expired items from the cache and returns how many were deleted.
+func (c *cache[T]) sweep() int {
+	c.rlock()
+	var removable []string
+	for k, i := range c.items {
+		if c.removable(i) {
+			removable = append(removable, k)
+		}
+	}
+	c.mutex.RUnlock()
+
+	c.lock()
+	defer c.mutex.Unlock()
+	count := c.deleteRemovable(removable)
+	c.rebuildFilter()
+	return count
+}
+
+// PurgeAtomic removes all expired items from the cache like Purge, but other operations see either all of the
+// expired items or none of them, never a partly purged cache. It copies the live items into a new map under the
+// read lock and only takes the write lock to swap it in, so it needs memory for a second copy of the live items
+// while it runs. If the cache is changed while the copy is made, it purges in place under the write lock instead.
+// If another purge is running, it waits for that one to finish before starting.
+func (c *cache[T]) PurgeAtomic() int {
+	f, _ := c.startPurge(context.Background())
+	defer c.endPurge(f)
+	c.rlock()
+	version, refreshes := c.version, c.refreshes
+	live := make(map[string]item[T], len(c.items))
+	var removed []string
+	for k, i := range c.items {
+		if c.removable(i) {
+			removed = append(removed, k)
+			continue
+		}
+		live[k] = i
+	}
+	count := len(removed)
+	c.mutex.RUnlock()
+
+	c.lock()
+	defer c.mutex.Unlock()
+	// The copies in live are stale if any item was written or refreshed since they were taken.
+	if c.version == version && c.refreshes == refreshes {
+		c.items = live
+		for _, k := range removed {
+			c.markDirty(k)
+		}
+	} else {
+		count = 0
+		for k, i := range c.items {
+			if c.removable(i) {
+				delete(c.items, k)
+				c.markDirty(k)
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		c.changed()
+		c.snapshot.Store(nil)
+	}
+	c.length.Store(int64(len(c.items)))
+	c.rebuildFilter()
+	f.value = count
+	return count
+}
+
+// PurgePreview returns the items that Purge would delete if it were called now, without deleting them: items whose TTL
+// has passed, beyond any grace period set with WithReadGrace, and items invalidated by NextGeneration.
+func (c *cache[T]) PurgePreview() []Entry[T] {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	var entries []Entry[T]
+	for k, i := range c.items {
+		if c.removable(i) {
+			entries = append(entries, Entry[T]{Key: k, Value: i.value, Expiration: i.expiration})
+		}
+	}
+	return entries
+}
+
+// snapshotItems returns the cached copy of the live items, rebuilding it if a write has happened since it was
+// made or if one of its items has since expired. A snapshot with a zero expiration holds no items and stays valid
+// until the next write. Expired items are skipped rather than deleted so that the snapshot can be built and stored
+// under a single read lock.
+func (c *cache[T]) snapshotItems() map[string]T {
+	s := c.snapshot.Load()
+	if s != nil && (s.expiration.IsZero() || time.Now().UTC().Before(s.expiration)) {
+		return s.items
+	}
+
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	s = &snapshot[T]{items: make(map[string]T, len(c.items))}
+	for k, i := range c.items {
+		if c.expired(i) {
+			continue
+		}
+		if s.expiration.IsZero() || i.expiration.Before(s.expiration) {
+			s.expiration = i.expiration
+		}
+		s.items[k] = i.value
+	}
+	c.snapshot.Store(s)
+	return s.items
+}
+
+// rejects returns true if the value is nil and the cache was created WithRejectNilValues.
+func (c *cache[T]) rejects(value T) bool {
+	return c.rejectNil && isNil(value)
+}
+
+// isNil returns true if v is nil, including a nil pointer, map, slice, func or channel held in an interface.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice, reflect.UnsafePointer:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// isNillable returns true if values of type t can be nil.
+func isNillable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice, reflect.UnsafePointer:
+		return true
+	default:
+		return false
+	}
+}
+
+// intern returns the canonical copy of key when the cache was created WithKeyInterning.
+func (c *cache[T]) intern(key string) string {
+	if !c.internKeys {
+		return key
+	}
+	return unique.Make(key).Value()
+}
+
+// newItem creates an item holding value that expires after the given TTL, or the default TTL if none is given.
+// It also returns whether the TTL had to be clamped to the cache's TTL bounds.
+func (c *cache[T]) newItem(value T, ttl ...time.Duration) (item[T], bool) {
+	created := time.Now().UTC()
+	expiration, clamped := c.clampExpiration(created, created, calculateExpiration(created, c.defaultTTL, ttl...))
+	return item[T]{
+		value:      value,
+		expiration: expiration,
+		created:    created,
+		written:    created,
+	}, clamped
+}
+
+// clampExpiration limits an expiration set at the given time to the cache's TTL bounds, and so that an item
+// created at the given time never outlives the cache's maximum lifetime.
+// It returns whether the expiration had to be changed to fit the TTL bounds.
+func (c *cache[T]) clampExpiration(now, created, expiration time.Time) (time.Time, bool) {
+	clamped := false
+	if c.minTTL > 0 {
+		if limit := now.Add(c.minTTL); expiration.Before(limit) {
+			expiration, clamped = limit, true
+		}
+	}
+	if c.maxTTL > 0 {
+		if limit := now.Add(c.maxTTL); expiration.After(limit) {
+			expiration, clamped = limit, true
+		}
+	}
+	if c.maxLifetime > 0 {
+		if limit := created.Add(c.maxLifetime); expiration.After(limit) {
+			expiration = limit
+		}
+	}
+	return expiration, clamped
+}
+
+// NextGeneration starts a new generation of the cache and returns its number. Every item written before it is
+// treated as expired straight away, without scanning the cache, and is deleted when it is next read or purged.
+// When the cache was created WithDirtyTracking, the keys of the items it expires are marked dirty.
+func (c *cache[T]) NextGeneration() uint64 {
+	c.lock()
+	defer c.mutex.Unlock()
+	if c.dirty != nil {
+		for k, i := range c.items {
+			if !c.expired(i) {
+				c.markDirty(k)
+			}
+		}
+	}
+	c.changed()
+	c.snapshot.Store(nil)
+	return c.generation.Add(1)
+}
+
+// expired returns true if the item's TTL has passed, or if it was written before the cache's current generation.
+func (c *cache[T]) expired(i item[T]) bool {
+	return c.outdated(i) || i.expired()
+}
+
+// removable returns true if the item has expired and is past the grace period set with WithReadGrace, so it can be
+// deleted from the cache.
+func (c *cache[T]) removable(i item[T]) bool {
+	if c.readGrace <= 0 || c.outdated(i) {
+		return c.expired(i)
+	}
+	return time.Now().UTC().After(i.expiration.Add(c.readGrace))
+}
+
+// changed records a change to the cache's items. The caller must hold the write lock.
+func (c *cache[T]) changed() {
+	c.version++
+	c.lastMutation.Store(time.Now().UnixNano())
+}
+
+// outdated returns true if the item was written before the cache's current generation.
+func (c *cache[T]) outdated(i item[T]) bool {
+	return i.generation < c.generation.Load()
+}
+
 type item[T any] struct {
 	value      T
 	expiration time.Time
+	created    time.Time
+	written    time.Time
+	generation uint64
+	origin     *Origin
 }
 
 func (i *item[T]) expired() bool {
 	return time.Now().UTC().After(i.expiration)
 }
 
+type snapshot[T any] struct {
+	items      map[string]T
+	expiration time.Time
+}
+
 type cache[T any] struct {
+	noCopy noCopy
+
 	items      map[string]item[T]
 	defaultTTL time.Duration
-	mutex      *sync.RWMutex
+	mutex      sync.RWMutex
+	snapshots  bool
+	snapshot   atomic.Pointer[snapshot[T]]
+	generation atomic.Uint64
+
+	snapshotLimit int
+	maxLifetime   time.Duration
+	lockStats     bool
+	stats         stats
+	// rejectNil is only true when T can be nil, so that rejects skips the check for other types, while rejectNilSet
+	// records that WithRejectNilValues was passed for Config.
+	rejectNil     bool
+	rejectNilSet  bool
+	minTTL        time.Duration
+	maxTTL        time.Duration
+	strictTTL     bool
+	internKeys    bool
+	trackOrigin   bool
+	originDepth   int
+	cleanupSample int
+	filter        atomic.Pointer[bloomFilter]
+	filterItems   int
+	filterRate    float64
+	dirty         map[string]struct{}
+	onReplace     func(key string, old, value T)
+	length        atomic.Int64
+	readOnlyGets  bool
+	readGrace     time.Duration
+	self          *cache[T]
+	janitor       *janitor
+	noOverwrite   bool
+	slowLoad      time.Duration
+	onSlowLoad    func(key string, took time.Duration)
+	equal         func(old, value T) bool
+	hasher        func(T) uint64
+
+	// version is incremented under the write lock by changed, on every change to items.
+	version uint64
+	// refreshes is incremented under the write lock when WithChangeDetection refreshes an item's expiration without
+	// changing its value, which does not count as a change.
+	refreshes uint64
+	// lastMutation is when items was last changed, in Unix nanoseconds.
+	lastMutation atomic.Int64
+
+	flights     map[string]*flight[T]
+	purging     *flight[int]
+	flightMutex sync.Mutex
+	keyLocks    keyLocks
 }
 
-func calculateExpiration(defaultTTL time.Duration, ttl ...time.Duration) time.Time {
-	t := time.Now().Add(defaultTTL).UTC()
+func calculateExpiration(now time.Time, defaultTTL time.Duration, ttl ...time.Duration) time.Time {
+	t := now.Add(defaultTTL).UTC()
 	givenValidTTL := len(ttl) > 0 && ttl[0] > 0
 	if givenValidTTL {
-		t = time.Now().Add(ttl[0]).UTC()
+		t = now.Add(ttl[0]).UTC()
 	}
 	return t
 }
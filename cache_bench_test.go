@@ -0,0 +1,263 @@
+package simcache
+
+import (
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func benchmarkItemsReadHeavy(b *testing.B, opts ...Option[int]) {
+	c := New[int](time.Hour, opts...)
+	for i := 0; i < 10000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		n := 0
+		for pb.Next() {
+			// One write for every thousand reads.
+			n++
+			if n%1000 == 0 {
+				c.Set("0", n)
+				continue
+			}
+			_ = c.Items()
+		}
+	})
+}
+
+func BenchmarkCache_Items(b *testing.B) {
+	benchmarkItemsReadHeavy(b)
+}
+
+func BenchmarkCache_ItemsSnapshot(b *testing.B) {
+	benchmarkItemsReadHeavy(b, WithItemsSnapshot[int]())
+}
+
+// benchmarkKeyChurn repeatedly sets a fixed set of keys, each sliced out of a freshly read 1KB request, and
+// reports the heap still in use once the cache is populated.
+func benchmarkKeyChurn(b *testing.B, opts ...Option[int]) {
+	const keys = 10000
+	c := New[int](time.Hour, opts...)
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < keys; i++ {
+			request := make([]byte, 1024)
+			key := strconv.AppendInt(request[:0], int64(i), 10)
+			c.Set(unsafe.String(&request[0], len(key)), i)
+		}
+	}
+	b.StopTimer()
+
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	b.ReportMetric(float64(m.HeapAlloc)/keys, "heap-B/key")
+	runtime.KeepAlive(c)
+}
+
+func BenchmarkCache_KeyChurn(b *testing.B) {
+	benchmarkKeyChurn(b)
+}
+
+func BenchmarkCache_KeyChurnInterned(b *testing.B) {
+	benchmarkKeyChurn(b, WithKeyInterning[int]())
+}
+
+func benchmarkGet(b *testing.B, opts ...Option[int]) {
+	c := New[int](time.Hour, opts...)
+	for i := 0; i < 10000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, _ = c.Get(strconv.Itoa(n % 10000))
+	}
+}
+
+func BenchmarkCache_Get(b *testing.B) {
+	benchmarkGet(b)
+}
+
+func BenchmarkCache_GetOpportunisticCleanup(b *testing.B) {
+	benchmarkGet(b, WithOpportunisticCleanup[int](3))
+}
+
+func benchmarkGetMisses(b *testing.B, opts ...Option[int]) {
+	c := New[int](time.Hour, opts...)
+	keys := make([]string, 1024)
+	for i := range keys {
+		// Nine misses for every hit.
+		keys[i] = "miss:" + strconv.Itoa(i)
+		if i%10 == 0 {
+			keys[i] = strconv.Itoa(i)
+			c.Set(keys[i], i)
+		}
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		n := 0
+		for pb.Next() {
+			n++
+			_, _ = c.Get(keys[n%len(keys)])
+		}
+	})
+}
+
+func BenchmarkCache_GetMisses(b *testing.B) {
+	benchmarkGetMisses(b)
+}
+
+func BenchmarkCache_GetMissesFiltered(b *testing.B) {
+	benchmarkGetMisses(b, WithMissFilter[int](10000, 0.01))
+}
+
+func benchmarkPurge(b *testing.B, purge func(c *Cache[int]) int) {
+	c := New[int](time.Hour)
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		for i := 0; i < 10000; i++ {
+			// Half of the items expire.
+			ttl := time.Hour
+			if i%2 == 0 {
+				ttl = time.Nanosecond
+			}
+			c.Set(strconv.Itoa(i), i, ttl)
+		}
+		time.Sleep(time.Nanosecond * 2)
+		b.StartTimer()
+		purge(c)
+	}
+}
+
+func BenchmarkCache_Purge(b *testing.B) {
+	benchmarkPurge(b, func(c *Cache[int]) int {
+		return c.Purge()
+	})
+}
+
+func BenchmarkCache_PurgeAtomic(b *testing.B) {
+	benchmarkPurge(b, func(c *Cache[int]) int {
+		return c.PurgeAtomic()
+	})
+}
+
+// BenchmarkCache_SetUnderReadLoad times Set while 64 goroutines call Get in a loop, and reports the 99th
+// percentile latency of the Sets. sync.RWMutex blocks new readers once a writer is waiting, so the writer only
+// waits for the reads already in progress rather than being starved by a stream of new ones.
+func BenchmarkCache_SetUnderReadLoad(b *testing.B) {
+	const readers = 64
+	c := New[int](time.Hour)
+	for i := 0; i < 10000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				_, _ = c.Get(strconv.Itoa(n % 10000))
+			}
+		}()
+	}
+
+	latencies := make([]time.Duration, b.N)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		start := time.Now()
+		c.Set("0", n)
+		latencies[n] = time.Since(start)
+	}
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+
+	slices.Sort(latencies)
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns/set")
+}
+
+// BenchmarkCache_SetUnderLongReads times Set while 64 goroutines call Get in a loop and 4 others repeatedly read every
+// item of a 100,000-item cache, and reports the 99th percentile latency of the Sets. A writer has to wait for every
+// read already holding the lock, so the long reads set the tail latency. The sub-benchmarks compare reading with Items,
+// which holds the read lock while copying the whole cache, against All, which only holds it for one item at a time.
+func BenchmarkCache_SetUnderLongReads(b *testing.B) {
+	type variant struct {
+		name string
+		read func(c *Cache[int])
+	}
+
+	variants := []variant{
+		{name: "Items", read: func(c *Cache[int]) { _ = c.Items() }},
+		{name: "All", read: func(c *Cache[int]) {
+			for range c.All() {
+			}
+		}},
+	}
+
+	for _, v := range variants {
+		b.Run(v.name, func(b *testing.B) {
+			const readers, longReaders, items = 64, 4, 100000
+			c := New[int](time.Hour)
+			for i := 0; i < items; i++ {
+				c.Set(strconv.Itoa(i), i)
+			}
+
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			for r := 0; r < readers+longReaders; r++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for n := 0; ; n++ {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						if r < longReaders {
+							v.read(c)
+							time.Sleep(time.Millisecond)
+						} else {
+							_, _ = c.Get(strconv.Itoa(n % items))
+							if n%100 == 0 {
+								time.Sleep(time.Millisecond)
+							}
+						}
+					}
+				}()
+			}
+
+			latencies := make([]time.Duration, b.N)
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				start := time.Now()
+				c.Set("0", n)
+				latencies[n] = time.Since(start)
+				// Writes are spread out, as in a read-heavy workload.
+				time.Sleep(time.Millisecond)
+			}
+			b.StopTimer()
+			close(done)
+			wg.Wait()
+
+			slices.Sort(latencies)
+			b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns/set")
+		})
+	}
+}
@@ -1,9 +1,20 @@
 package simcache
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 )
 
 type pair[T any] struct {
@@ -71,6 +82,121 @@ func TestCache_Add(t *testing.T) {
 	}
 }
 
+func TestCache_AddReplacesExpired(t *testing.T) {
+	type unitTest struct {
+		name   string
+		c      *Cache[int]
+		expire func(c *Cache[int])
+	}
+
+	tests := []unitTest{
+		{
+			name:   "Previous Generation",
+			c:      New[int](time.Hour),
+			expire: func(c *Cache[int]) { c.NextGeneration() },
+		},
+		{
+			name:   "Expired Read Only",
+			c:      New[int](time.Nanosecond, WithReadOnlyGets[int]()),
+			expire: func(c *Cache[int]) { time.Sleep(time.Millisecond) },
+		},
+	}
+
+	for _, test := range tests {
+		test.c.Set("key", 1)
+		test.expire(test.c)
+		if added := test.c.Add("key", 2, time.Hour); !added {
+			t.Fatalf("%s FAILED - expected the expired item to be replaced", test.name)
+		}
+		if value, found := test.c.Get("key"); !found || value != 2 {
+			t.Fatalf("%s FAILED - expected %d but got %d (found %t)", test.name, 2, value, found)
+		}
+	}
+}
+
+func TestCache_AddConcurrent(t *testing.T) {
+	const goroutines = 50
+	for round := 0; round < 100; round++ {
+		c := New[int](time.Hour)
+		start := make(chan struct{})
+		var added atomic.Int32
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				if c.Add("key", g) {
+					added.Add(1)
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if n := added.Load(); n != 1 {
+			t.Fatalf("Add FAILED - expected %d successful adds but got %d", 1, n)
+		}
+	}
+}
+
+func TestCache_GetOrSet(t *testing.T) {
+	type unitTest struct {
+		name          string
+		setup         func(c *Cache[int])
+		expected      int
+		expectedFound bool
+	}
+
+	tests := []unitTest{
+		{name: "Absent", setup: func(c *Cache[int]) {}, expected: 2, expectedFound: false},
+		{name: "Present", setup: func(c *Cache[int]) { c.Set("one", 1) }, expected: 1, expectedFound: true},
+		{name: "Expired", setup: func(c *Cache[int]) { c.Set("one", 1, time.Nanosecond) }, expected: 2, expectedFound: false},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		test.setup(c)
+		time.Sleep(time.Millisecond)
+		actual, found := c.GetOrSet("one", 2)
+		if actual != test.expected || found != test.expectedFound {
+			t.Fatalf("%s FAILED - expected %d, %t but got %d, %t", test.name, test.expected, test.expectedFound, actual, found)
+		}
+		if stored, _ := c.Get("one"); stored != test.expected {
+			t.Fatalf("%s FAILED - expected %d stored but got %d", test.name, test.expected, stored)
+		}
+	}
+}
+
+func TestCache_GetOrSetConcurrent(t *testing.T) {
+	for round := 0; round < 1000; round++ {
+		c := New[int](time.Hour)
+		start := make(chan struct{})
+		results := make([]int, 2)
+		inserted := make([]bool, 2)
+		var wg sync.WaitGroup
+		for g := 0; g < 2; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				var found bool
+				results[g], found = c.GetOrSet("key", g+1)
+				inserted[g] = !found
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if inserted[0] == inserted[1] {
+			t.Fatalf("GetOrSet FAILED - expected exactly one insert but got %t and %t", inserted[0], inserted[1])
+		}
+		if results[0] != results[1] {
+			t.Fatalf("GetOrSet FAILED - expected both goroutines to get the same value but got %d and %d", results[0], results[1])
+		}
+	}
+}
+
 func TestCache_Set(t *testing.T) {
 	c := New[int](time.Hour)
 	c.Set("a", 1)
@@ -202,6 +328,88 @@ func TestCache_Delete(t *testing.T) {
 	}
 }
 
+func TestCache_DeleteAndReturn(t *testing.T) {
+	type unitTest struct {
+		name     string
+		key      string
+		expected int
+		found    bool
+	}
+
+	tests := []unitTest{
+		{name: "Live", key: "one", expected: 1, found: true},
+		{name: "Expired", key: "expired", expected: 0, found: false},
+		{name: "Missing", key: "missing", expected: 0, found: false},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		c.Set("one", 1)
+		c.Set("expired", 2, time.Nanosecond)
+		time.Sleep(time.Nanosecond * 2)
+
+		value, found := c.DeleteAndReturn(test.key)
+		if value != test.expected || found != test.found {
+			t.Fatalf("%s FAILED - expected %d, %t but got %d, %t", test.name, test.expected, test.found, value, found)
+		}
+		if _, found := c.items[test.key]; found {
+			t.Fatalf("%s FAILED - %q was not deleted", test.name, test.key)
+		}
+	}
+}
+
+func TestCache_DeleteAllIfPresent(t *testing.T) {
+	type unitTest struct {
+		name     string
+		keys     []string
+		expected bool
+		length   int
+	}
+
+	tests := []unitTest{
+		{
+			name:     "All Present",
+			keys:     []string{"0", "1", "2"},
+			expected: true,
+			length:   2,
+		},
+		{
+			name:     "One Missing",
+			keys:     []string{"0", "1", "missing"},
+			expected: false,
+			length:   5,
+		},
+		{
+			name:     "One Expired",
+			keys:     []string{"0", "expired"},
+			expected: false,
+			length:   5,
+		},
+		{
+			name:     "No Keys",
+			keys:     nil,
+			expected: true,
+			length:   5,
+		},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		for _, p := range makePairs[int](4) {
+			c.Set(p.key, p.value)
+		}
+		c.Set("expired", 0, time.Nanosecond)
+		time.Sleep(time.Nanosecond * 2)
+
+		if deleted := c.DeleteAllIfPresent(test.keys); deleted != test.expected {
+			t.Fatalf("%s FAILED - expected %t but got %t", test.name, test.expected, deleted)
+		}
+		if length := len(c.items); length != test.length {
+			t.Fatalf("%s FAILED - expected %d items but got %d", test.name, test.length, length)
+		}
+	}
+}
+
 func TestCache_Items(t *testing.T) {
 	type unitTest struct {
 		name  string
@@ -338,11 +546,1288 @@ func TestCache_Purge(t *testing.T) {
 	}
 }
 
-func contains[T comparable](target T, s []T) bool {
-	for _, actual := range s {
-		if actual == target {
-			return true
+func TestCache_ExpiredItemsDoNotDeadlock(t *testing.T) {
+	type unitTest struct {
+		name string
+		fn   func(c *Cache[int])
+	}
+
+	tests := []unitTest{
+		{name: "Purge", fn: func(c *Cache[int]) { c.Purge() }},
+		{name: "Items", fn: func(c *Cache[int]) { c.Items() }},
+		{name: "Values", fn: func(c *Cache[int]) { c.Values() }},
+		{name: "Get", fn: func(c *Cache[int]) { c.Get("0") }},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		for i := 0; i < 3; i++ {
+			c.Set(strconv.Itoa(i), i+1, time.Nanosecond)
+		}
+		c.Set("live", 4)
+		time.Sleep(time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			test.fn(c)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second * 5):
+			t.Fatalf("%s FAILED - did not return with expired items in the cache", test.name)
+		}
+
+		if test.name != "Get" && c.Len() != 1 {
+			t.Fatalf("%s FAILED - expected %d items but got %d", test.name, 1, c.Len())
+		}
+		if value, found := c.Get("1"); found || value != 0 {
+			t.Fatalf("%s FAILED - expected %d, %t but got %d, %t", test.name, 0, false, value, found)
+		}
+		if c.ApproxLen() != int64(c.Len()) {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, c.Len(), c.ApproxLen())
+		}
+	}
+}
+
+func TestCache_ConcurrentPurge(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 3; i++ {
+		c.Set(strconv.Itoa(i), i, time.Nanosecond)
+	}
+	c.Set("live", 3)
+	time.Sleep(time.Nanosecond * 2)
+
+	// Start a purge that only scans the cache once release is closed, so the others start while it is running.
+	first, _ := c.beginPurge()
+	release := make(chan struct{})
+	firstCount := make(chan int, 1)
+	go func() {
+		<-release
+		first.value = c.sweep()
+		c.endPurge(first)
+		firstCount <- first.value
+	}()
+
+	if _, err := c.TryPurge(); err != ErrPurgeInProgress {
+		t.Fatalf("FAILED - expected %v but got %v", ErrPurgeInProgress, err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if count, err := c.PurgeCtx(cancelled); count != 0 || err != context.Canceled {
+		t.Fatalf("FAILED - expected %d, %v but got %d, %v", 0, context.Canceled, count, err)
+	}
+
+	type result struct {
+		name  string
+		count int
+	}
+	results := make(chan result, 3)
+	go func() {
+		results <- result{name: "Purge", count: c.Purge()}
+	}()
+	go func() {
+		results <- result{name: "PurgeAtomic", count: c.PurgeAtomic()}
+	}()
+	go func() {
+		count, _ := c.PurgeCtx(context.Background())
+		results <- result{name: "PurgeCtx", count: count}
+	}()
+	close(release)
+
+	// Had any other purge run alongside the first, it would have deleted some of the expired items itself.
+	if count := <-firstCount; count != 3 {
+		t.Fatalf("FAILED - expected %d but got %d", 3, count)
+	}
+	for i := 0; i < 3; i++ {
+		// Purge returns the count of the purge it waited on; the others wait and then find nothing left to delete.
+		r := <-results
+		if r.count != 0 && (r.name != "Purge" || r.count != 3) {
+			t.Fatalf("%s FAILED - expected %d but got %d", r.name, 0, r.count)
+		}
+	}
+	if count, err := c.TryPurge(); count != 0 || err != nil {
+		t.Fatalf("FAILED - expected %d, %v but got %d, %v", 0, nil, count, err)
+	}
+}
+
+func TestCache_SnapshotLimit(t *testing.T) {
+	type unitTest struct {
+		name     string
+		length   int
+		expected error
+	}
+
+	tests := []unitTest{
+		{
+			name:     "Under Limit",
+			length:   2,
+			expected: nil,
+		},
+		{
+			name:     "At Limit",
+			length:   3,
+			expected: nil,
+		},
+		{
+			name:     "Over Limit",
+			length:   4,
+			expected: ErrTooManyEntries,
+		},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour, WithSnapshotLimit[int](3))
+		for _, p := range makePairs[int](test.length) {
+			c.Set(p.key, p.value)
+		}
+		// Expired items do not count towards the limit.
+		c.Set("expired", 0, time.Nanosecond)
+		time.Sleep(time.Nanosecond * 2)
+
+		items, err := c.ItemsChecked()
+		if err != test.expected {
+			t.Fatalf("%s FAILED - ItemsChecked expected %v but got %v", test.name, test.expected, err)
+		}
+		if err == nil && len(items) != test.length {
+			t.Fatalf("%s FAILED - ItemsChecked expected %d but got %d", test.name, test.length, len(items))
+		}
+
+		keys, err := c.KeysChecked()
+		if err != test.expected {
+			t.Fatalf("%s FAILED - KeysChecked expected %v but got %v", test.name, test.expected, err)
+		}
+		if err == nil && len(keys) != test.length {
+			t.Fatalf("%s FAILED - KeysChecked expected %d but got %d", test.name, test.length, len(keys))
+		}
+
+		values, err := c.ValuesChecked()
+		if err != test.expected {
+			t.Fatalf("%s FAILED - ValuesChecked expected %v but got %v", test.name, test.expected, err)
+		}
+		if err == nil && len(values) != test.length {
+			t.Fatalf("%s FAILED - ValuesChecked expected %d but got %d", test.name, test.length, len(values))
+		}
+	}
+}
+
+func TestCache_MaxLifetime(t *testing.T) {
+	c := New[int](time.Hour, WithMaxLifetime[int](time.Minute))
+	c.Set("default", 1)
+	c.Set("long", 2, time.Hour*24)
+	c.Set("short", 3, time.Second)
+
+	type unitTest struct {
+		name     string
+		key      string
+		expected time.Duration
+	}
+
+	tests := []unitTest{
+		{
+			name:     "Default TTL",
+			key:      "default",
+			expected: time.Minute,
+		},
+		{
+			name:     "Longer TTL",
+			key:      "long",
+			expected: time.Minute,
+		},
+		{
+			name:     "Shorter TTL",
+			key:      "short",
+			expected: time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		i := c.items[test.key]
+		actual := i.expiration.Sub(i.created)
+		if actual != test.expected {
+			t.Fatalf("%s FAILED - expected %s but got %s", test.name, test.expected, actual)
+		}
+	}
+
+	c = New[int](time.Hour, WithMaxLifetime[int](time.Nanosecond))
+	c.Set("one", 1)
+	time.Sleep(time.Nanosecond * 2)
+	if _, found := c.Get("one"); found {
+		t.Fatal(`FAILED - "one" was found after its maximum lifetime`)
+	}
+}
+
+func TestCache_Collect(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("task:1", 1)
+	c.Set("task:3", 3)
+	c.Set("task:4", 4, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	found, missing := c.Collect([]string{"task:1", "task:2", "task:3", "task:4"})
+	if len(found) != 2 || found["task:1"] != 1 || found["task:3"] != 3 {
+		t.Fatalf("FAILED - unexpected results %v", found)
+	}
+	if expected := []string{"task:2", "task:4"}; !slices.Equal(missing, expected) {
+		t.Fatalf("FAILED - expected %v but got %v", expected, missing)
+	}
+
+	c.Set("task:2", 2)
+	c.Set("task:4", 4)
+	if found, missing := c.Collect([]string{"task:1", "task:2", "task:3", "task:4"}); len(found) != 4 || missing != nil {
+		t.Fatalf("FAILED - expected all results but got %v, missing %v", found, missing)
+	}
+}
+
+func TestCache_TTLMany(t *testing.T) {
+	c := New[int](time.Hour)
+	for _, p := range makePairs[int](100) {
+		c.Set(p.key, p.value)
+	}
+	c.Set("expired", 0, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	ttls := c.TTLMany("0", "50", "99", "expired", "missing")
+	if len(ttls) != 3 {
+		t.Fatalf("FAILED - expected %d but got %d", 3, len(ttls))
+	}
+	assertConsistentTTLs(t, ttls, time.Hour)
+}
+
+func TestCache_TTLAll(t *testing.T) {
+	c := New[int](time.Hour)
+	for _, p := range makePairs[int](100) {
+		c.Set(p.key, p.value)
+	}
+	c.Set("expired", 0, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	ttls := c.TTLAll()
+	if len(ttls) != 100 {
+		t.Fatalf("FAILED - expected %d but got %d", 100, len(ttls))
+	}
+	assertConsistentTTLs(t, ttls, time.Hour)
+}
+
+// assertConsistentTTLs checks that the TTLs of items set one after another with the same TTL differ by no more
+// than the time it took to set them, and never exceed that TTL.
+func assertConsistentTTLs(t *testing.T, ttls map[string]time.Duration, ttl time.Duration) {
+	t.Helper()
+	var lowest, highest time.Duration
+	for _, d := range ttls {
+		if d > ttl {
+			t.Fatalf("FAILED - TTL %s is greater than %s", d, ttl)
+		}
+		if lowest == 0 || d < lowest {
+			lowest = d
+		}
+		if d > highest {
+			highest = d
+		}
+	}
+	if spread := highest - lowest; spread > time.Millisecond*100 {
+		t.Fatalf("FAILED - TTLs spread over %s", spread)
+	}
+}
+
+func TestCache_RejectNilValues(t *testing.T) {
+	var nilPointer *int
+	var nilReader io.Reader = (*bytes.Buffer)(nil)
+
+	pointers := New[*int](time.Hour, WithRejectNilValues[*int]())
+	if pointers.Add("nil", nil) {
+		t.Fatal("FAILED - Add stored a nil pointer")
+	}
+	if err := pointers.TrySet("nil", nilPointer); err != ErrNilValue {
+		t.Fatalf("FAILED - expected %v but got %v", ErrNilValue, err)
+	}
+	pointers.Set("nil", nil)
+	if _, found := pointers.Get("nil"); found {
+		t.Fatal("FAILED - Set stored a nil pointer")
+	}
+	one := 1
+	if err := pointers.TrySet("one", &one); err != nil {
+		t.Fatalf("FAILED - expected %v but got %v", nil, err)
+	}
+
+	readers := New[io.Reader](time.Hour, WithRejectNilValues[io.Reader]())
+	if readers.Add("nil", nil) {
+		t.Fatal("FAILED - Add stored a nil interface")
+	}
+	if readers.Add("typed nil", nilReader) {
+		t.Fatal("FAILED - Add stored a nil pointer inside an interface")
+	}
+	if !readers.Add("buffer", &bytes.Buffer{}) {
+		t.Fatal("FAILED - Add rejected a non-nil value")
+	}
+
+	slices := New[[]int](time.Hour, WithRejectNilValues[[]int]())
+	if slices.Add("nil", nil) {
+		t.Fatal("FAILED - Add stored a nil slice")
+	}
+	if !slices.Add("empty", []int{}) {
+		t.Fatal("FAILED - Add rejected an empty slice")
+	}
+
+	ints := New[int](time.Hour, WithRejectNilValues[int]())
+	if !ints.Add("zero", 0) {
+		t.Fatal("FAILED - Add rejected a value that cannot be nil")
+	}
+
+	pointers = New[*int](time.Hour)
+	if !pointers.Add("nil", nil) {
+		t.Fatal("FAILED - Add rejected a nil pointer without WithRejectNilValues")
+	}
+}
+
+func TestCache_TTLBounds(t *testing.T) {
+	type write struct {
+		name string
+		fn   func(c *Cache[int], ttl ...time.Duration) error
+	}
+
+	writes := []write{
+		{
+			name: "Set",
+			fn: func(c *Cache[int], ttl ...time.Duration) error {
+				c.Set("key", 1, ttl...)
+				return nil
+			},
+		},
+		{
+			name: "Add",
+			fn: func(c *Cache[int], ttl ...time.Duration) error {
+				c.Add("key", 1, ttl...)
+				return nil
+			},
+		},
+		{
+			name: "TrySet",
+			fn: func(c *Cache[int], ttl ...time.Duration) error {
+				return c.TrySet("key", 1, ttl...)
+			},
+		},
+	}
+
+	type unitTest struct {
+		name       string
+		defaultTTL time.Duration
+		ttl        []time.Duration
+		expected   time.Duration
+		clamped    bool
+	}
+
+	tests := []unitTest{
+		{
+			name:       "Default Within Bounds",
+			defaultTTL: time.Hour,
+			expected:   time.Hour,
+		},
+		{
+			name:       "Default Over Max",
+			defaultTTL: time.Hour * 48,
+			expected:   time.Hour * 24,
+			clamped:    true,
+		},
+		{
+			name:       "Default Under Min",
+			defaultTTL: time.Second,
+			expected:   time.Minute,
+			clamped:    true,
+		},
+		{
+			name:       "TTL Within Bounds",
+			defaultTTL: time.Hour,
+			ttl:        []time.Duration{time.Hour * 2},
+			expected:   time.Hour * 2,
+		},
+		{
+			name:       "TTL Over Max",
+			defaultTTL: time.Hour,
+			ttl:        []time.Duration{time.Hour * 10000},
+			expected:   time.Hour * 24,
+			clamped:    true,
+		},
+		{
+			name:       "TTL Under Min",
+			defaultTTL: time.Hour,
+			ttl:        []time.Duration{time.Second},
+			expected:   time.Minute,
+			clamped:    true,
+		},
+	}
+
+	for _, w := range writes {
+		for _, test := range tests {
+			c := New[int](test.defaultTTL, WithMinTTL[int](time.Minute), WithMaxTTL[int](time.Hour*24))
+			if err := w.fn(c, test.ttl...); err != nil {
+				t.Fatalf("%s %s FAILED - expected %v but got %v", w.name, test.name, nil, err)
+			}
+			i := c.items["key"]
+			if actual := i.expiration.Sub(i.created); actual != test.expected {
+				t.Fatalf("%s %s FAILED - expected %s but got %s", w.name, test.name, test.expected, actual)
+			}
+			if actual := c.Stats().ClampedWrites == 1; actual != test.clamped {
+				t.Fatalf("%s %s FAILED - expected %t but got %t", w.name, test.name, test.clamped, actual)
+			}
+		}
+	}
+
+	for _, test := range tests {
+		c := New[int](test.defaultTTL, WithMinTTL[int](time.Minute), WithMaxTTL[int](time.Hour*24), WithStrictTTL[int]())
+		err := c.TrySet("key", 1, test.ttl...)
+		if actual := err == ErrTTLOutOfRange; actual != test.clamped {
+			t.Fatalf("Strict %s FAILED - expected %t but got %t", test.name, test.clamped, actual)
+		}
+		if _, found := c.Get("key"); found == test.clamped {
+			t.Fatalf("Strict %s FAILED - expected %t but got %t", test.name, !test.clamped, found)
+		}
+	}
+}
+
+func TestNew_InvalidTTLBounds(t *testing.T) {
+	type unitTest struct {
+		name     string
+		min, max time.Duration
+		panics   bool
+	}
+
+	tests := []unitTest{
+		{name: "Min Over Max", min: time.Hour, max: time.Minute, panics: true},
+		{name: "Min Equals Max", min: time.Hour, max: time.Hour, panics: false},
+		{name: "Min Under Max", min: time.Minute, max: time.Hour, panics: false},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); (r != nil) != test.panics {
+					t.Fatalf("%s FAILED - expected a panic %t but got %v", test.name, test.panics, r)
+				}
+			}()
+			New[int](time.Hour, WithMinTTL[int](test.min), WithMaxTTL[int](test.max))
+		}()
+	}
+}
+
+func TestCache_NoOverwrite(t *testing.T) {
+	type unitTest struct {
+		name     string
+		setup    func(c *Cache[int])
+		expected error
+	}
+
+	tests := []unitTest{
+		{name: "Absent", setup: func(c *Cache[int]) {}, expected: nil},
+		{name: "Present", setup: func(c *Cache[int]) { c.Set("one", 1) }, expected: ErrKeyExists},
+		{name: "Expired", setup: func(c *Cache[int]) { c.Set("one", 1, time.Nanosecond) }, expected: nil},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour, WithNoOverwrite[int]())
+		test.setup(c)
+		time.Sleep(time.Millisecond)
+		if err := c.TrySet("one", 2); err != test.expected {
+			t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, err)
+		}
+
+		c = New[int](time.Hour, WithNoOverwrite[int]())
+		test.setup(c)
+		time.Sleep(time.Millisecond)
+		func() {
+			defer func() {
+				if r := recover(); (r != nil) != (test.expected != nil) {
+					t.Fatalf("%s FAILED - expected a panic %t but got %v", test.name, test.expected != nil, r)
+				}
+			}()
+			c.Set("one", 2)
+		}()
+
+		expected := 2
+		if test.expected != nil {
+			expected = 1
+		}
+		if actual, _ := c.Get("one"); actual != expected {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, expected, actual)
+		}
+	}
+}
+
+func TestCache_KeyInterning(t *testing.T) {
+	a := New[int](time.Hour, WithKeyInterning[int]())
+	b := New[int](time.Hour, WithKeyInterning[int]())
+	request := []byte("key and the rest of a large request")
+	key := string(request[:3])
+	a.Set(key, 1)
+	b.Add(key, 2)
+
+	keyA := a.Keys()[0]
+	keyB := b.Keys()[0]
+	if keyA != key || keyB != key {
+		t.Fatalf("FAILED - expected %q but got %q and %q", key, keyA, keyB)
+	}
+	if unsafe.StringData(keyA) != unsafe.StringData(keyB) {
+		t.Fatal("FAILED - expected both caches to share the interned key")
+	}
+	if unsafe.StringData(keyA) == unsafe.StringData(key) {
+		t.Fatal("FAILED - expected the interned key not to share storage with the key passed in")
+	}
+}
+
+func TestCache_ChangeDetection(t *testing.T) {
+	type unitTest struct {
+		name        string
+		c           *Cache[[]int]
+		invalidated bool
+	}
+
+	tests := []unitTest{
+		{
+			name:        "Without Detection",
+			c:           New[[]int](time.Hour, WithItemsSnapshot[[]int]()),
+			invalidated: true,
+		},
+		{
+			name:        "With Detection",
+			c:           New[[]int](time.Hour, WithItemsSnapshot[[]int](), WithChangeDetection(DeepEqual[[]int])),
+			invalidated: false,
+		},
+	}
+
+	for _, test := range tests {
+		test.c.Set("one", []int{1}, time.Minute)
+		before := test.c.Items()
+		expiration := test.c.items["one"].expiration
+		for i := 0; i < 10; i++ {
+			test.c.Set("one", []int{1})
+		}
+		after := test.c.Items()
+		invalidated := reflect.ValueOf(before).Pointer() != reflect.ValueOf(after).Pointer()
+		if invalidated != test.invalidated {
+			t.Fatalf("%s FAILED - expected %t but got %t", test.name, test.invalidated, invalidated)
+		}
+		if !test.c.items["one"].expiration.After(expiration) {
+			t.Fatalf("%s FAILED - expected the expiration to be refreshed", test.name)
+		}
+
+		test.c.Set("one", []int{2})
+		if value, _ := test.c.Get("one"); value[0] != 2 {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, 2, value[0])
+		}
+		if reflect.ValueOf(after).Pointer() == reflect.ValueOf(test.c.Items()).Pointer() {
+			t.Fatalf("%s FAILED - expected a changed value to invalidate the snapshot", test.name)
+		}
+
+		// Refreshing to a shorter TTL must not leave the item in the snapshot after it expires.
+		_ = test.c.Items()
+		test.c.Set("one", []int{2}, time.Millisecond)
+		time.Sleep(time.Millisecond * 2)
+		if items := test.c.Items(); len(items) != 0 {
+			t.Fatalf("%s FAILED - expected %d items but got %v", test.name, 0, items)
+		}
+	}
+
+	c := New[int](time.Hour, WithChangeDetection(DeepEqual[int]))
+	c.Set("one", 1)
+	before := c.LastMutation()
+	time.Sleep(time.Millisecond)
+	c.Set("one", 1, time.Minute)
+	if !c.LastMutation().Equal(before) {
+		t.Fatal("FAILED - expected an unchanged write not to move LastMutation")
+	}
+}
+
+func TestCache_ReplaceFunc(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	c.Set("expired", 0, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	expiration := c.items["2"].expiration
+
+	even := func(key string, value int) bool {
+		return value%2 == 0
+	}
+	count := c.ReplaceFunc(even, -1)
+	if count != 5 {
+		t.Fatalf("FAILED - expected %d but got %d", 5, count)
+	}
+	for i := 0; i < 10; i++ {
+		expected := i
+		if i%2 == 0 {
+			expected = -1
+		}
+		if value, _ := c.Get(strconv.Itoa(i)); value != expected {
+			t.Fatalf("FAILED - expected %d but got %d", expected, value)
+		}
+	}
+	if c.items["2"].expiration != expiration {
+		t.Fatal("FAILED - expected the expiration to be kept")
+	}
+
+	one := 1
+	pointers := New[*int](time.Hour, WithRejectNilValues[*int]())
+	pointers.Set("one", &one)
+	if count := pointers.ReplaceFunc(func(string, *int) bool { return true }, nil); count != 0 {
+		t.Fatalf("FAILED - expected %d but got %d", 0, count)
+	}
+	if value, _ := pointers.Get("one"); value != &one {
+		t.Fatalf("FAILED - expected the rejected nil not to be stored but got %v", value)
+	}
+}
+
+func TestCache_ReplaceAll(t *testing.T) {
+	c := New[int](time.Hour, WithMissFilter[int](16, 0.01))
+	c.Set("one", 1)
+	c.Set("two", 2)
+	c.Set("expired", 3, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	var replaced []string
+	c.OnReplace(func(key string, old, value int) {
+		replaced = append(replaced, key+":"+strconv.Itoa(old)+"->"+strconv.Itoa(value))
+	})
+
+	old := c.ReplaceAll(map[string]int{"two": 20, "three": 30, "expired": 40}, time.Minute)
+	if len(old) != 2 || old["one"] != 1 || old["two"] != 2 {
+		t.Fatalf("FAILED - unexpected old items %v", old)
+	}
+	if !slices.Equal(replaced, []string{"two:2->20"}) {
+		t.Fatalf("FAILED - expected %v but got %v", []string{"two:2->20"}, replaced)
+	}
+	if items := c.Items(); len(items) != 3 || items["two"] != 20 || items["three"] != 30 {
+		t.Fatalf("FAILED - unexpected items %v", items)
+	}
+	if value, found := c.Get("three"); !found || value != 30 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 30, value, found)
+	}
+	if ttl := time.Until(c.items["two"].expiration); ttl > time.Minute {
+		t.Fatalf("FAILED - expected a TTL of one minute but got %s", ttl)
+	}
+	if length := c.ApproxLen(); length != 3 {
+		t.Fatalf("FAILED - expected %d but got %d", 3, length)
+	}
+}
+
+func TestCache_ReplaceAllConsistency(t *testing.T) {
+	datasets := []map[string]int{make(map[string]int), make(map[string]int)}
+	for i := 0; i < 100; i++ {
+		datasets[0]["a"+strconv.Itoa(i)] = 1
+	}
+	for i := 0; i < 50; i++ {
+		datasets[1]["b"+strconv.Itoa(i)] = 2
+	}
+	c := New[int](time.Hour)
+	c.ReplaceAll(datasets[0])
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				items := c.Items()
+				if !maps.Equal(items, datasets[0]) && !maps.Equal(items, datasets[1]) {
+					t.Errorf("FAILED - read a partial dataset of %d items", len(items))
+					return
+				}
+			}
+		}()
+	}
+	for n := 0; n < 200; n++ {
+		c.ReplaceAll(datasets[n%2])
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestCache_ExpireAll(t *testing.T) {
+	c := New[int](time.Hour)
+	for _, p := range makePairs[int](5) {
+		c.Set(p.key, p.value)
+	}
+
+	count := c.ExpireAll()
+	if count != 5 {
+		t.Fatalf("FAILED - expected %d but got %d", 5, count)
+	}
+	if length := len(c.items); length != 5 {
+		t.Fatalf("FAILED - expected %d items to be kept but got %d", 5, length)
+	}
+	time.Sleep(time.Nanosecond * 2)
+	if _, found := c.Get("0"); found {
+		t.Fatal(`FAILED - "0" was found after ExpireAll`)
+	}
+	if count := c.Purge(); count != 4 {
+		t.Fatalf("FAILED - expected %d but got %d", 4, count)
+	}
+}
+
+func TestCache_ReadOnlyGets(t *testing.T) {
+	c := New[int](time.Hour, WithReadOnlyGets[int](), WithOpportunisticCleanup[int](10))
+	c.Set("one", 1)
+	c.Set("expired", 2, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	// Get must not need the write lock while another reader holds the read lock.
+	c.mutex.RLock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, found := c.Get("expired"); found {
+			t.Error(`FAILED - "expired" was found`)
+		}
+		c.Get("one")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("FAILED - Get waited for the write lock")
+	}
+	c.mutex.RUnlock()
+
+	if _, found := c.items["expired"]; !found {
+		t.Fatal(`FAILED - "expired" was deleted by Get`)
+	}
+	if count := c.Purge(); count != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, count)
+	}
+}
+
+func TestCache_GetFresh(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("new", 1)
+	c.Set("old", 2)
+	c.Set("expired", 3, time.Nanosecond)
+	old := c.items["old"]
+	old.created = time.Now().UTC().Add(-time.Minute)
+	c.items["old"] = old
+	time.Sleep(time.Nanosecond * 2)
+
+	if value, found := c.GetFresh("new", time.Second*5); !found || value != 1 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 1, value, found)
+	}
+	if _, found := c.GetFresh("old", time.Second*5); found {
+		t.Fatal(`FAILED - "old" was found when older than the maximum age`)
+	}
+	if value, found := c.GetFresh("old", time.Minute*5); !found || value != 2 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 2, value, found)
+	}
+	if _, found := c.Get("old"); !found {
+		t.Fatal(`FAILED - "old" was not left in the cache`)
+	}
+	if _, found := c.GetFresh("expired", time.Hour); found {
+		t.Fatal(`FAILED - "expired" was found`)
+	}
+	if _, found := c.GetFresh("missing", time.Hour); found {
+		t.Fatal(`FAILED - "missing" was found`)
+	}
+}
+
+func TestCache_GetWithExpiration(t *testing.T) {
+	c := New[int](time.Minute)
+	before := time.Now()
+	c.Set("one", 1, time.Hour)
+	c.Set("expired", 2, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	value, expiration, found := c.GetWithExpiration("one")
+	if !found || value != 1 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 1, value, found)
+	}
+	if expected := before.Add(time.Hour); expiration.Before(expected) || expiration.After(expected.Add(time.Second)) {
+		t.Fatalf("FAILED - expected about %s but got %s", expected, expiration)
+	}
+
+	value, expiration, found = c.GetWithExpiration("expired")
+	if found || value != 0 || !expiration.IsZero() {
+		t.Fatalf("FAILED - expected %d, %s, %t but got %d, %s, %t", 0, time.Time{}, false, value, expiration, found)
+	}
+	if c.Len() != 1 {
+		t.Fatal(`FAILED - "expired" was not deleted like Get would`)
+	}
+
+	if _, expiration, found = c.GetWithExpiration("missing"); found || !expiration.IsZero() {
+		t.Fatal(`FAILED - "missing" was found`)
+	}
+}
+
+func TestCache_ApplyTTL(t *testing.T) {
+	c := New[int](time.Minute, WithMaxTTL[int](time.Hour*2))
+	c.Set("session:1", 1)
+	c.Set("session:2", 2)
+	c.Set("user:1", 3)
+	c.Set("expired", 4, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	expiration := c.items["user:1"].expiration
+
+	count := c.ApplyTTL(func(key string) (time.Duration, bool) {
+		if key == "expired" {
+			return time.Hour, true
+		}
+		return time.Hour * 24, strings.HasPrefix(key, "session:")
+	})
+	if count != 2 {
+		t.Fatalf("FAILED - expected %d but got %d", 2, count)
+	}
+	for _, k := range []string{"session:1", "session:2"} {
+		if ttl := time.Until(c.items[k].expiration); ttl <= time.Hour || ttl > time.Hour*2 {
+			t.Fatalf("FAILED - expected %s to be clamped to two hours but got %s", k, ttl)
+		}
+	}
+	if c.items["user:1"].expiration != expiration {
+		t.Fatal(`FAILED - expected "user:1" to keep its expiration`)
+	}
+	if _, found := c.Get("expired"); found {
+		t.Fatal(`FAILED - "expired" was brought back`)
+	}
+}
+
+func TestCache_ReadGrace(t *testing.T) {
+	c := New[int](time.Hour, WithReadGrace[int](time.Minute))
+	c.Set("live", 1)
+	c.Set("stale", 2)
+	c.Set("gone", 3)
+	now := time.Now().UTC()
+	stale := c.items["stale"]
+	stale.expiration = now.Add(-time.Second)
+	c.items["stale"] = stale
+	gone := c.items["gone"]
+	gone.expiration = now.Add(-time.Hour)
+	c.items["gone"] = gone
+
+	// Reads that do not ask for stale items treat them as expired, but leave them in the cache.
+	if _, found := c.Get("stale"); found {
+		t.Fatal(`FAILED - "stale" was found by Get`)
+	}
+	if _, found := c.GetAndTouch("stale"); found {
+		t.Fatal(`FAILED - "stale" was found by GetAndTouch`)
+	}
+
+	// Purge only deletes items once their grace period has passed.
+	if count := c.Purge(); count != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, count)
+	}
+	if _, found, _ := c.GetStale("stale"); !found {
+		t.Fatal(`FAILED - "stale" was purged within the grace period`)
+	}
+	if items := c.Items(); len(items) != 1 || items["live"] != 1 {
+		t.Fatalf("FAILED - unexpected items %v", items)
+	}
+	if _, found := c.items["stale"]; !found {
+		t.Fatal(`FAILED - "stale" was deleted within the grace period`)
+	}
+
+	type unitTest struct {
+		key   string
+		value int
+		found bool
+		stale bool
+	}
+
+	tests := []unitTest{
+		{key: "live", value: 1, found: true, stale: false},
+		{key: "stale", value: 2, found: true, stale: true},
+		{key: "gone", value: 0, found: false, stale: false},
+		{key: "missing", value: 0, found: false, stale: false},
+	}
+
+	for _, test := range tests {
+		value, found, isStale := c.GetStale(test.key)
+		if value != test.value || found != test.found || isStale != test.stale {
+			t.Fatalf("%s FAILED - expected %d, %t, %t but got %d, %t, %t",
+				test.key, test.value, test.found, test.stale, value, found, isStale)
+		}
+	}
+
+	c.NextGeneration()
+	if _, found, _ := c.GetStale("live"); found {
+		t.Fatal(`FAILED - "live" was found after NextGeneration`)
+	}
+	if count := c.Purge(); count != 2 {
+		t.Fatalf("FAILED - expected %d but got %d", 2, count)
+	}
+}
+
+func TestCache_GetAndTouch(t *testing.T) {
+	c := New[int](time.Minute)
+	c.Set("one", 1, time.Second)
+
+	value, found := c.GetAndTouch("one")
+	if !found || value != 1 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 1, value, found)
+	}
+	i := c.items["one"]
+	if ttl := time.Until(i.expiration); ttl <= time.Second || ttl > time.Minute {
+		t.Fatalf("FAILED - expected the default TTL but got %s", ttl)
+	}
+
+	c.GetAndTouch("one", time.Hour)
+	if ttl := time.Until(c.items["one"].expiration); ttl <= time.Minute {
+		t.Fatalf("FAILED - expected a TTL of one hour but got %s", ttl)
+	}
+
+	if _, found := c.GetAndTouch("missing"); found {
+		t.Fatal(`FAILED - "missing" was found`)
+	}
+
+	c.Set("expired", 2, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	if _, found := c.GetAndTouch("expired"); found {
+		t.Fatal(`FAILED - "expired" was found`)
+	}
+	if _, found := c.items["expired"]; found {
+		t.Fatal(`FAILED - "expired" was not deleted`)
+	}
+
+	c = New[int](time.Minute, WithMaxLifetime[int](time.Minute*2))
+	c.Set("one", 1)
+	c.GetAndTouch("one", time.Hour)
+	if i := c.items["one"]; i.expiration.Sub(i.created) != time.Minute*2 {
+		t.Fatalf("FAILED - expected the maximum lifetime to cap the TTL")
+	}
+
+	c = New[int](time.Hour, WithItemsSnapshot[int](), WithDirtyTracking[int]())
+	c.Set("one", 1)
+	c.Set("expired", 2, time.Nanosecond)
+	_ = c.Items()
+	c.GetAndTouch("one", time.Millisecond)
+	time.Sleep(time.Millisecond * 2)
+	if items := c.Items(); len(items) != 0 {
+		t.Fatalf("Snapshot FAILED - expected %d but got %d", 0, len(items))
+	}
+	c.ClearDirty()
+	c.GetAndTouch("expired")
+	if dirty := c.DirtyKeys(); !slices.Equal(dirty, []string{"expired"}) {
+		t.Fatalf("Dirty FAILED - expected %v but got %v", []string{"expired"}, dirty)
+	}
+}
+
+func TestCache_NextGeneration(t *testing.T) {
+	c := New[int](time.Hour, WithItemsSnapshot[int](), WithDirtyTracking[int]())
+	for _, p := range makePairs[int](5) {
+		c.Set(p.key, p.value)
+	}
+	_ = c.Items()
+	c.ClearDirty()
+	before := c.LastMutation()
+	time.Sleep(time.Millisecond)
+
+	if generation := c.NextGeneration(); generation != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, generation)
+	}
+	if !c.LastMutation().After(before) {
+		t.Fatalf("FAILED - expected LastMutation to move past %s but got %s", before, c.LastMutation())
+	}
+	if dirty := c.DirtyKeys(); len(dirty) != 5 {
+		t.Fatalf("FAILED - expected %d dirty keys but got %v", 5, dirty)
+	}
+	c.Set("new", 1)
+
+	if _, found := c.Get("0"); found {
+		t.Fatal(`FAILED - "0" from the previous generation was found`)
+	}
+	if value, found := c.Get("new"); !found || value != 1 {
+		t.Fatal(`FAILED - "new" from the current generation was not found`)
+	}
+	if items := c.Items(); len(items) != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, len(items))
+	}
+	if ttls := c.TTLAll(); len(ttls) != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, len(ttls))
+	}
+
+	// "0" was deleted by Get, the other four by Purge.
+	if count := c.Purge(); count != 4 {
+		t.Fatalf("FAILED - expected %d but got %d", 4, count)
+	}
+	if length := len(c.items); length != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, length)
+	}
+}
+
+func TestCache_SetIfStillPresent(t *testing.T) {
+	type unitTest struct {
+		name     string
+		write    func(c *Cache[int], key string, value int)
+		expected bool
+	}
+
+	tests := []unitTest{
+		{
+			name: "Set",
+			write: func(c *Cache[int], key string, value int) {
+				c.Set(key, value)
+			},
+			expected: true,
+		},
+		{
+			name: "SetIfStillPresent",
+			write: func(c *Cache[int], key string, value int) {
+				c.SetIfStillPresent(key, value)
+			},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		c.Set("deleted", 1)
+		c.Set("expired", 2, time.Millisecond)
+		c.Set("kept", 3)
+
+		// Read a snapshot, invalidate some of it during a long computation, then write the results back.
+		items := c.Items()
+		c.Delete("deleted")
+		time.Sleep(time.Millisecond * 2)
+		for k, v := range items {
+			test.write(c, k, v*10)
+		}
+
+		for _, key := range []string{"deleted", "expired"} {
+			if _, found := c.Get(key); found != test.expected {
+				t.Fatalf("%s FAILED - expected %q to be found %t but got %t", test.name, key, test.expected, found)
+			}
+		}
+		if value, _ := c.Get("kept"); value != 30 {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, 30, value)
+		}
+	}
+}
+
+func TestCache_GroupBy(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	c.Set("expired", 10, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	groups := c.GroupBy(func(key string, value int) string {
+		if value%2 == 0 {
+			return "even"
+		}
+		return "odd"
+	})
+	if len(groups) != 2 {
+		t.Fatalf("FAILED - expected %d but got %d", 2, len(groups))
+	}
+	for name, entries := range groups {
+		if len(entries) != 5 {
+			t.Fatalf("FAILED - expected %d %s entries but got %d", 5, name, len(entries))
+		}
+		for _, e := range entries {
+			if (e.Value%2 == 0) != (name == "even") || e.Key != strconv.Itoa(e.Value) {
+				t.Fatalf("FAILED - %+v is in the wrong group %s", e, name)
+			}
+		}
+	}
+}
+
+func TestCache_PurgeAtomic(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 10; i++ {
+		c.Set(strconv.Itoa(i), i, time.Nanosecond)
+		c.Set("live"+strconv.Itoa(i), i)
+	}
+	time.Sleep(time.Nanosecond * 2)
+
+	if count := c.PurgeAtomic(); count != 10 {
+		t.Fatalf("FAILED - expected %d but got %d", 10, count)
+	}
+	if length := len(c.items); length != 10 {
+		t.Fatalf("FAILED - expected %d but got %d", 10, length)
+	}
+	if _, found := c.Get("live0"); !found {
+		t.Fatal(`FAILED - "live0" was not found`)
+	}
+}
+
+func TestCache_PurgePreview(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("outdated", 0)
+	c.NextGeneration()
+	for i := 1; i < 10; i++ {
+		ttl := time.Hour
+		if i%3 == 0 {
+			ttl = time.Nanosecond
+		}
+		c.Set(strconv.Itoa(i), i, ttl)
+	}
+	time.Sleep(time.Nanosecond * 2)
+
+	preview := c.PurgePreview()
+	if length := len(c.items); length != 10 {
+		t.Fatalf("FAILED - expected %d items to be kept but got %d", 10, length)
+	}
+	keys := make([]string, 0, len(preview))
+	for _, e := range preview {
+		if e.Value != c.items[e.Key].value {
+			t.Fatalf("FAILED - unexpected entry %+v", e)
+		}
+		keys = append(keys, e.Key)
+	}
+	slices.Sort(keys)
+	if expected := []string{"3", "6", "9", "outdated"}; !slices.Equal(keys, expected) {
+		t.Fatalf("FAILED - expected %v but got %v", expected, keys)
+	}
+
+	if count := c.Purge(); count != len(preview) {
+		t.Fatalf("FAILED - expected %d but got %d", len(preview), count)
+	}
+	for _, k := range keys {
+		if _, found := c.items[k]; found {
+			t.Fatalf("FAILED - %q was previewed but not purged", k)
+		}
+	}
+}
+
+func TestCache_PurgeAtomicConsistency(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i, time.Millisecond*5)
+		c.Set("live"+strconv.Itoa(i), i)
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	// Count the items readers can see while a purge runs: every expired item is still there, or none are.
+	done := make(chan struct{})
+	observed := make(chan int, 1)
+	go func() {
+		defer close(observed)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			c.rlock()
+			length := len(c.items)
+			c.mutex.RUnlock()
+			if length != 2000 && length != 1000 {
+				observed <- length
+				return
+			}
+		}
+	}()
+
+	// Keep writing to the live items so the purge falls back to purging in place some of the time.
+	for n := 0; n < 10; n++ {
+		go c.Set("live0", n)
+		c.PurgeAtomic()
+	}
+	close(done)
+	if length, partial := <-observed; partial {
+		t.Fatalf("FAILED - observed a partly purged cache with %d items", length)
+	}
+}
+
+func contains[T comparable](target T, s []T) bool {
+	for _, actual := range s {
+		if actual == target {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCache_ItemsSnapshot(t *testing.T) {
+	c := New[int](time.Hour, WithItemsSnapshot[int]())
+	c.Set("one", 1)
+	c.Set("two", 2)
+
+	first := c.Items()
+	second := c.Items()
+	if reflect.ValueOf(first).Pointer() != reflect.ValueOf(second).Pointer() {
+		t.Fatal("FAILED - expected repeated calls to Items to return the same snapshot")
+	}
+	if len(first) != 2 || first["one"] != 1 || first["two"] != 2 {
+		t.Fatalf("FAILED - unexpected snapshot contents %v", first)
+	}
+
+	c.Set("three", 3)
+	third := c.Items()
+	if reflect.ValueOf(second).Pointer() == reflect.ValueOf(third).Pointer() {
+		t.Fatal("FAILED - expected a write to invalidate the snapshot")
+	}
+	if len(third) != 3 || third["three"] != 3 {
+		t.Fatalf("FAILED - unexpected snapshot contents %v", third)
+	}
+
+	c.Set("four", 4, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	fourth := c.Items()
+	if _, found := fourth["four"]; found {
+		t.Fatal(`FAILED - "four" was in the snapshot after it expired`)
+	}
+	fifth := c.Items()
+	if reflect.ValueOf(fourth).Pointer() != reflect.ValueOf(fifth).Pointer() {
+		t.Fatal("FAILED - expected the rebuilt snapshot to be reused")
+	}
+}
+
+func TestCache_ItemsIncludingStale(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("outdated", 4)
+	c.NextGeneration()
+	c.Set("live", 1)
+	c.Set("recent", 2)
+	c.Set("old", 3)
+	now := time.Now().UTC()
+	recent := c.items["recent"]
+	recent.expiration = now.Add(-time.Second)
+	c.items["recent"] = recent
+	old := c.items["old"]
+	old.expiration = now.Add(-time.Hour)
+	c.items["old"] = old
+
+	items := c.ItemsIncludingStale(time.Minute)
+	if len(items) != 2 || items["live"] != 1 || items["recent"] != 2 {
+		t.Fatalf("FAILED - unexpected items %v", items)
+	}
+	if items := c.Items(); len(items) != 1 {
+		t.Fatalf("FAILED - expected Items to stay strict but got %v", items)
+	}
+}
+
+func TestCache_ApproxLen(t *testing.T) {
+	c := New[int64](time.Hour, WithOpportunisticCleanup[int64](4))
+	ops := []func(key string){
+		func(key string) { c.Set(key, 1) },
+		func(key string) { c.Set(key, 1, time.Microsecond) },
+		func(key string) { c.Add(key, 1) },
+		func(key string) { c.Get(key) },
+		func(key string) { c.GetAndTouch(key) },
+		func(key string) { c.Delete(key) },
+		func(key string) { c.DeleteAllIfPresent([]string{key, "0"}) },
+		func(key string) { IncrementWithCap(c, key, 1, 10) },
+		func(string) { c.ExpireAll() },
+		func(string) { c.Purge() },
+		func(string) { c.PurgeAtomic() },
+		func(string) { _, _ = c.PurgeCtx(context.Background()) },
+	}
+
+	for round := 0; round < 20; round++ {
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					ops[rand.IntN(len(ops))](strconv.Itoa(rand.IntN(20)))
+				}
+			}()
+		}
+		wg.Wait()
+
+		if approx, length := c.ApproxLen(), c.Len(); approx != int64(length) {
+			t.Fatalf("FAILED - round %d: expected %d but got %d", round, length, approx)
 		}
 	}
-	return false
 }
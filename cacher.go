@@ -0,0 +1,218 @@
+package simcache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Cacher is the set of operations shared by Cache and the decorators that wrap it.
+type Cacher[T any] interface {
+	Add(key string, value T, ttl ...time.Duration) bool
+	Set(key string, value T, ttl ...time.Duration)
+	Get(key string) (T, bool)
+	Delete(key string)
+	Items() map[string]T
+	Keys() []string
+	Values() []T
+	Purge() int
+}
+
+var _ Cacher[int] = (*Cache[int])(nil)
+
+// reportingSetter is implemented by Cache and the decorators in this package, whose Set can report whether the value
+// was written, so that decorators do not report writes the cache rejected. skip is the number of frames between the
+// caller of setAndReport and the code that called Set, so that WithOriginTracking records the code outside the
+// decorators.
+type reportingSetter[T any] interface {
+	setAndReport(skip int, key string, value T, ttl ...time.Duration) bool
+}
+
+// setAndReport calls Set on c and returns whether the value was written. When c cannot report it, the value is assumed
+// to have been written. skip is the number of frames between the caller of setAndReport and the code that called Set.
+func setAndReport[T any](c Cacher[T], skip int, key string, value T, ttl ...time.Duration) bool {
+	if s, ok := c.(reportingSetter[T]); ok {
+		return s.setAndReport(skip+1, key, value, ttl...)
+	}
+	c.Set(key, value, ttl...)
+	return true
+}
+
+// setAndReport stores the value like Set, and returns false if the cache's options rejected it.
+func (c *cache[T]) setAndReport(skip int, key string, value T, ttl ...time.Duration) bool {
+	err := c.set(key, value, false, skip+1, ttl...)
+	if errors.Is(err, ErrKeyExists) {
+		panicOverwrite(key)
+	}
+	return err == nil
+}
+
+// Decorator wraps a Cacher to add behaviour to it.
+type Decorator[T any] func(Cacher[T]) Cacher[T]
+
+// Chain wraps c with each of the decorators in order, so the last decorator is the outermost.
+func Chain[T any](c Cacher[T], decorators ...Decorator[T]) Cacher[T] {
+	for _, d := range decorators {
+		c = d(c)
+	}
+	return c
+}
+
+// MetricsHook holds the functions called by an Instrumented cache. Any of them may be nil.
+type MetricsHook struct {
+	// OnHit is called when Get finds a key.
+	OnHit func(key string)
+	// OnMiss is called when Get does not find a key.
+	OnMiss func(key string)
+	// OnWrite is called when Set or Add writes a key. It is not called for values the cache rejected.
+	OnWrite func(key string)
+	// OnDelete is called when Delete removes a key.
+	OnDelete func(key string)
+	// OnLatency is called with how long each operation took, named by its method, including Items, Keys and Values.
+	OnLatency func(op string, took time.Duration)
+}
+
+// Instrumented returns a Cacher that passes the operations made through it on to c, and calls hooks after each returns.
+func Instrumented[T any](c Cacher[T], hooks MetricsHook) Cacher[T] {
+	return &instrumented[T]{Cacher: c, hooks: hooks}
+}
+
+type instrumented[T any] struct {
+	Cacher[T]
+	hooks MetricsHook
+}
+
+func (c *instrumented[T]) Add(key string, value T, ttl ...time.Duration) bool {
+	defer c.latency("Add", time.Now())
+	added := c.Cacher.Add(key, value, ttl...)
+	if added && c.hooks.OnWrite != nil {
+		c.hooks.OnWrite(key)
+	}
+	return added
+}
+
+func (c *instrumented[T]) Set(key string, value T, ttl ...time.Duration) {
+	c.setAndReport(0, key, value, ttl...)
+}
+
+func (c *instrumented[T]) setAndReport(skip int, key string, value T, ttl ...time.Duration) bool {
+	defer c.latency("Set", time.Now())
+	written := setAndReport(c.Cacher, skip+1, key, value, ttl...)
+	if written && c.hooks.OnWrite != nil {
+		c.hooks.OnWrite(key)
+	}
+	return written
+}
+
+func (c *instrumented[T]) Get(key string) (T, bool) {
+	defer c.latency("Get", time.Now())
+	value, found := c.Cacher.Get(key)
+	if found && c.hooks.OnHit != nil {
+		c.hooks.OnHit(key)
+	}
+	if !found && c.hooks.OnMiss != nil {
+		c.hooks.OnMiss(key)
+	}
+	return value, found
+}
+
+func (c *instrumented[T]) Delete(key string) {
+	defer c.latency("Delete", time.Now())
+	c.Cacher.Delete(key)
+	if c.hooks.OnDelete != nil {
+		c.hooks.OnDelete(key)
+	}
+}
+
+func (c *instrumented[T]) Items() map[string]T {
+	defer c.latency("Items", time.Now())
+	return c.Cacher.Items()
+}
+
+func (c *instrumented[T]) Keys() []string {
+	defer c.latency("Keys", time.Now())
+	return c.Cacher.Keys()
+}
+
+func (c *instrumented[T]) Values() []T {
+	defer c.latency("Values", time.Now())
+	return c.Cacher.Values()
+}
+
+func (c *instrumented[T]) Purge() int {
+	defer c.latency("Purge", time.Now())
+	return c.Cacher.Purge()
+}
+
+func (c *instrumented[T]) latency(op string, start time.Time) {
+	if c.hooks.OnLatency != nil {
+		c.hooks.OnLatency(op, time.Since(start))
+	}
+}
+
+// Logged returns a Cacher that passes the operations made through it on to c, and logs each at debug level after it
+// returns.
+func Logged[T any](c Cacher[T], l *slog.Logger) Cacher[T] {
+	return &logged[T]{Cacher: c, logger: l}
+}
+
+type logged[T any] struct {
+	Cacher[T]
+	logger *slog.Logger
+}
+
+func (c *logged[T]) Add(key string, value T, ttl ...time.Duration) bool {
+	added := c.Cacher.Add(key, value, ttl...)
+	c.log("Add", slog.String("key", key), slog.Bool("added", added))
+	return added
+}
+
+func (c *logged[T]) Set(key string, value T, ttl ...time.Duration) {
+	c.setAndReport(0, key, value, ttl...)
+}
+
+func (c *logged[T]) setAndReport(skip int, key string, value T, ttl ...time.Duration) bool {
+	written := setAndReport(c.Cacher, skip+1, key, value, ttl...)
+	c.log("Set", slog.String("key", key), slog.Bool("written", written))
+	return written
+}
+
+func (c *logged[T]) Get(key string) (T, bool) {
+	value, found := c.Cacher.Get(key)
+	c.log("Get", slog.String("key", key), slog.Bool("found", found))
+	return value, found
+}
+
+func (c *logged[T]) Delete(key string) {
+	c.Cacher.Delete(key)
+	c.log("Delete", slog.String("key", key))
+}
+
+func (c *logged[T]) Items() map[string]T {
+	items := c.Cacher.Items()
+	c.log("Items", slog.Int("count", len(items)))
+	return items
+}
+
+func (c *logged[T]) Keys() []string {
+	keys := c.Cacher.Keys()
+	c.log("Keys", slog.Int("count", len(keys)))
+	return keys
+}
+
+func (c *logged[T]) Values() []T {
+	values := c.Cacher.Values()
+	c.log("Values", slog.Int("count", len(values)))
+	return values
+}
+
+func (c *logged[T]) Purge() int {
+	count := c.Cacher.Purge()
+	c.log("Purge", slog.Int("deleted", count))
+	return count
+}
+
+func (c *logged[T]) log(op string, attrs ...slog.Attr) {
+	c.logger.LogAttrs(context.Background(), slog.LevelDebug, "simcache "+op, attrs...)
+}
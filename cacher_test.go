@@ -0,0 +1,147 @@
+package simcache
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixed is a Decorator used to test Chain that stores every key under a prefix.
+type prefixed[T any] struct {
+	Cacher[T]
+	prefix string
+}
+
+func (c *prefixed[T]) Set(key string, value T, ttl ...time.Duration) {
+	c.Cacher.Set(c.prefix+key, value, ttl...)
+}
+
+func (c *prefixed[T]) Get(key string) (T, bool) {
+	return c.Cacher.Get(c.prefix + key)
+}
+
+func TestChain(t *testing.T) {
+	inner := New[int](time.Hour)
+
+	var hits, misses, writes int
+	var ops []string
+	hooks := MetricsHook{
+		OnHit:   func(key string) { hits++ },
+		OnMiss:  func(key string) { misses++ },
+		OnWrite: func(key string) { writes++ },
+		OnLatency: func(op string, took time.Duration) {
+			ops = append(ops, op)
+		},
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := Chain[int](inner,
+		func(c Cacher[int]) Cacher[int] { return &prefixed[int]{Cacher: c, prefix: "tenant:"} },
+		func(c Cacher[int]) Cacher[int] { return Instrumented(c, hooks) },
+		func(c Cacher[int]) Cacher[int] { return Logged(c, logger) },
+	)
+
+	c.Set("one", 1)
+	value, found := c.Get("one")
+	if !found || value != 1 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 1, value, found)
+	}
+	if _, found := c.Get("two"); found {
+		t.Fatal(`FAILED - "two" was found`)
+	}
+
+	// Prefixed layer.
+	if _, found := inner.Get("tenant:one"); !found {
+		t.Fatal(`FAILED - expected the inner cache to hold "tenant:one"`)
+	}
+	// Instrumented layer.
+	if hits != 1 || misses != 1 || writes != 1 {
+		t.Fatalf("FAILED - expected 1 hit, miss and write but got %d, %d and %d", hits, misses, writes)
+	}
+	if strings.Join(ops, ",") != "Set,Get,Get" {
+		t.Fatalf("FAILED - expected %q but got %q", "Set,Get,Get", strings.Join(ops, ","))
+	}
+	// Logged layer.
+	logs := buf.String()
+	for _, expected := range []string{`msg="simcache Set" key=one`, `msg="simcache Get" key=one found=true`, `msg="simcache Get" key=two found=false`} {
+		if !strings.Contains(logs, expected) {
+			t.Fatalf("FAILED - expected the log to contain %q but got %q", expected, logs)
+		}
+	}
+}
+
+func TestInstrumented_RejectedWrite(t *testing.T) {
+	inner := New[*int](time.Hour, WithRejectNilValues[*int]())
+	var writes int
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c := Instrumented(Logged[*int](inner, logger), MetricsHook{OnWrite: func(key string) { writes++ }})
+
+	one := 1
+	c.Set("one", &one)
+	c.Set("nil", nil)
+	if writes != 1 {
+		t.Fatalf("FAILED - expected %d write but got %d", 1, writes)
+	}
+	logs := buf.String()
+	for _, expected := range []string{`msg="simcache Set" key=one written=true`, `msg="simcache Set" key=nil written=false`} {
+		if !strings.Contains(logs, expected) {
+			t.Fatalf("FAILED - expected the log to contain %q but got %q", expected, logs)
+		}
+	}
+
+	// A decorator that cannot report rejected writes is assumed to have written the value.
+	c = Instrumented[*int](&prefixed[*int]{Cacher: inner, prefix: "tenant:"}, MetricsHook{OnWrite: func(key string) { writes++ }})
+	c.Set("nil", nil)
+	if writes != 2 {
+		t.Fatalf("FAILED - expected %d writes but got %d", 2, writes)
+	}
+}
+
+func TestInstrumented_Origin(t *testing.T) {
+	type unitTest struct {
+		name     string
+		decorate func(c Cacher[int]) Cacher[int]
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []unitTest{
+		{name: "Instrumented", decorate: func(c Cacher[int]) Cacher[int] { return Instrumented(c, MetricsHook{}) }},
+		{name: "Logged", decorate: func(c Cacher[int]) Cacher[int] { return Logged(c, logger) }},
+		{
+			name: "Chain",
+			decorate: func(c Cacher[int]) Cacher[int] {
+				return Logged(Instrumented(c, MetricsHook{}), logger)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		inner := New[int](time.Hour, WithOriginTracking[int](0))
+		c := test.decorate(inner)
+		c.Set("key", 1)
+		expected := line() - 1
+		meta, _ := inner.Meta("key")
+		if meta.Origin == nil || !strings.HasSuffix(meta.Origin.File, "cacher_test.go") || meta.Origin.Line != expected {
+			t.Fatalf("%s FAILED - expected cacher_test.go:%d but got %s", test.name, expected, meta.Origin)
+		}
+	}
+}
+
+func TestInstrumented_Latency(t *testing.T) {
+	var ops []string
+	c := Instrumented[int](New[int](time.Hour), MetricsHook{OnLatency: func(op string, took time.Duration) {
+		ops = append(ops, op)
+	}})
+
+	c.Items()
+	c.Keys()
+	c.Values()
+	if strings.Join(ops, ",") != "Items,Keys,Values" {
+		t.Fatalf("FAILED - expected %q but got %q", "Items,Keys,Values", strings.Join(ops, ","))
+	}
+}
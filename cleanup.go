@@ -0,0 +1,52 @@
+package simcache
+
+// cleanup deletes any expired items among a random sample of the cache's items, when the cache was created
+// WithOpportunisticCleanup. The sample is taken under the read lock, and the write lock is only taken when an
+// expired item was found.
+func (c *cache[T]) cleanup() {
+	if c.cleanupSample <= 0 {
+		return
+	}
+
+	var expired []string
+	c.rlock()
+	sampled := 0
+	// Map iteration starts at a random position, so the first items visited are a random sample.
+	for k, i := range c.items {
+		if sampled == c.cleanupSample {
+			break
+		}
+		sampled++
+		if c.removable(i) {
+			expired = append(expired, k)
+		}
+	}
+	c.mutex.RUnlock()
+	if len(expired) == 0 {
+		return
+	}
+
+	c.lock()
+	defer c.mutex.Unlock()
+	c.deleteRemovable(expired)
+}
+
+// deleteRemovable deletes the items for the given keys that are still removable, and returns how many were deleted.
+// The keys are collected under the read lock, so an item may have been written again or deleted by the time the
+// caller takes the write lock; such items are left alone. The caller must hold the write lock.
+func (c *cache[T]) deleteRemovable(keys []string) int {
+	count := 0
+	for _, k := range keys {
+		if i, found := c.items[k]; found && c.removable(i) {
+			delete(c.items, k)
+			c.changed()
+			c.markDirty(k)
+			count++
+		}
+	}
+	if count > 0 {
+		c.length.Store(int64(len(c.items)))
+		c.snapshot.Store(nil)
+	}
+	return count
+}
@@ -0,0 +1,45 @@
+package simcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCache_OpportunisticCleanup(t *testing.T) {
+	type unitTest struct {
+		name    string
+		c       *Cache[int]
+		bounded bool
+	}
+
+	tests := []unitTest{
+		{
+			name:    "Without Cleanup",
+			c:       New[int](time.Hour),
+			bounded: false,
+		},
+		{
+			name:    "With Cleanup",
+			c:       New[int](time.Hour, WithOpportunisticCleanup[int](3)),
+			bounded: true,
+		},
+	}
+
+	for _, test := range tests {
+		test.c.Set("live", 1)
+		// Every read is matched by a write of an item that expires straight away.
+		for i := 0; i < 1000; i++ {
+			test.c.Set(strconv.Itoa(i), i, time.Nanosecond)
+			if _, found := test.c.Get("live"); !found {
+				t.Fatalf(`%s FAILED - "live" was not found`, test.name)
+			}
+		}
+		time.Sleep(time.Nanosecond * 2)
+
+		bounded := len(test.c.items) < 100
+		if bounded != test.bounded {
+			t.Fatalf("%s FAILED - expected %t but got %t with %d items", test.name, test.bounded, bounded, len(test.c.items))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package simcache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLoadPanicked is returned by GetOrCompute and Memoize to callers that were waiting on a call to a load function
+// that panicked. The caller that made the call panics with the original value instead.
+var ErrLoadPanicked = errors.New("simcache: load function panicked")
+
+// Memoize returns a function that caches the results of fn in the cache, keyed by keyFn.
+// Concurrent calls with inputs that share a key only call fn once, and errors returned by fn are not cached.
+// If fn panics, the call that ran it panics and the calls waiting on it return an error wrapping ErrLoadPanicked.
+func Memoize[In comparable, Out any](c *Cache[Out], keyFn func(In) string, fn func(In) (Out, error)) func(In) (Out, error) {
+	return func(in In) (Out, error) {
+		return c.GetOrCompute(keyFn(in), func() (Out, error) {
+			return fn(in)
+		})
+	}
+}
+
+// flight is a call to a compute function that other callers for the same key can wait on.
+type flight[T any] struct {
+	wg    sync.WaitGroup
+	value T
+	err   error
+}
+
+// GetOrCompute returns the live value for a given key, calling fn to produce it if it is not in the cache and storing
+// the result with the given TTL, or the default TTL if none is given.
+// fn is called without holding the cache's lock, so it can be slow, and concurrent calls for the same key share a
+// single call to fn. If fn returns an error, nothing is stored and the error is returned to every caller waiting on it.
+// If fn panics, the caller that called it panics, and the callers waiting on it get an error wrapping ErrLoadPanicked.
+// When the cache was created WithNoOverwrite and another write stored the key while fn ran, that value is returned
+// instead of the result of fn.
+func (c *cache[T]) GetOrCompute(key string, fn func() (T, error), ttl ...time.Duration) (T, error) {
+	return c.load(key, fn, func(value T) T {
+		if errors.Is(c.set(key, value, false, 0, ttl...), ErrKeyExists) {
+			if live, found := c.Get(key); found {
+				return live
+			}
+		}
+		return value
+	})
+}
+
+// Once returns the value stored for a given key, calling fn to produce and store it if the key is not in the cache.
+// Unlike GetOrCompute, the value is stored without an expiration, ignoring the default TTL and the bounds set by
+// WithMaxTTL and WithMaxLifetime, so fn is called once for the life of the cache. Concurrent callers share that one
+// call. The value is only computed again if it is removed, for example with Delete, ExpireAll or NextGeneration.
+// If fn panics, nothing is stored and every caller sharing the call panics.
+func (c *cache[T]) Once(key string, fn func() T) T {
+	value, err := c.load(key, func() (T, error) {
+		return fn(), nil
+	}, c.storeForever(key))
+	if err != nil {
+		// fn cannot return an error, so fn panicked in the caller this one was waiting on.
+		panic(err)
+	}
+	return value
+}
+
+// storeForever returns a function that stores a value for a given key so that it never expires, and returns the value
+// left in the cache. When the cache was created WithNoOverwrite and a live item is already stored, it is kept and its
+// value returned.
+func (c *cache[T]) storeForever(key string) func(value T) T {
+	return func(value T) T {
+		if c.rejects(value) {
+			return value
+		}
+		key = c.intern(key)
+		i, _ := c.newItem(value)
+		i.expiration = neverExpires
+		c.lock()
+		defer c.mutex.Unlock()
+		if old, found := c.items[key]; found && c.noOverwrite && !c.expired(old) {
+			return old.value
+		}
+		c.store(key, i, false)
+		return value
+	}
+}
+
+// neverExpires is the expiration of items stored by Once.
+var neverExpires = time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC)
+
+// load returns the live value for a given key, calling fn to produce it and passing the result to store if it is not in
+// the cache. store returns the value left in the cache, which is returned to every caller. fn is called without holding
+// the cache's lock, and concurrent calls for the same key share a single call to fn. If fn returns an error, nothing is
+// stored and the error is returned to every caller waiting on it.
+func (c *cache[T]) load(key string, fn func() (T, error), store func(value T) T) (T, error) {
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+
+	c.flightMutex.Lock()
+	if f, found := c.flights[key]; found {
+		c.flightMutex.Unlock()
+		f.wg.Wait()
+		return f.value, f.err
+	}
+	f := &flight[T]{}
+	f.wg.Add(1)
+	if c.flights == nil {
+		c.flights = make(map[string]*flight[T])
+	}
+	c.flights[key] = f
+	c.flightMutex.Unlock()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			f.value, f.err = *new(T), fmt.Errorf("%w: %v", ErrLoadPanicked, r)
+		}
+		c.flightMutex.Lock()
+		delete(c.flights, key)
+		c.flightMutex.Unlock()
+		f.wg.Done()
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	// Another caller may have stored the value between the lookup above and this flight starting.
+	if value, found := c.Get(key); found {
+		f.value = value
+		return f.value, nil
+	}
+	start := time.Now()
+	f.value, f.err = fn()
+	c.recordLoad(key, time.Since(start))
+	if f.err == nil {
+		f.value = store(f.value)
+	}
+	return f.value, f.err
+}
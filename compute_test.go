@@ -0,0 +1,303 @@
+package simcache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoize(t *testing.T) {
+	c := New[string](time.Hour)
+	var calls atomic.Int32
+	square := Memoize(c, strconv.Itoa, func(in int) (string, error) {
+		calls.Add(1)
+		return strconv.Itoa(in * in), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		out, err := square(4)
+		if err != nil || out != "16" {
+			t.Fatalf("FAILED - expected %q but got %q, %v", "16", out, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("FAILED - expected %d calls but got %d", 1, n)
+	}
+	if out, found := c.Get("4"); !found || out != "16" {
+		t.Fatalf("FAILED - expected the result to be cached")
+	}
+}
+
+func TestMemoize_Concurrent(t *testing.T) {
+	c := New[int](time.Hour)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	slow := Memoize(c, strconv.Itoa, func(in int) (int, error) {
+		calls.Add(1)
+		<-release
+		return in, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := slow(1); err != nil || out != 1 {
+				t.Errorf("FAILED - expected %d but got %d, %v", 1, out, err)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("FAILED - expected %d calls but got %d", 1, n)
+	}
+}
+
+func TestMemoize_Error(t *testing.T) {
+	c := New[int](time.Hour)
+	var calls atomic.Int32
+	failing := Memoize(c, strconv.Itoa, func(in int) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errors.New("failed")
+		}
+		return in, nil
+	})
+
+	if _, err := failing(1); err == nil {
+		t.Fatal("FAILED - expected an error")
+	}
+	if _, found := c.Get("1"); found {
+		t.Fatal("FAILED - an error result was cached")
+	}
+	if out, err := failing(1); err != nil || out != 1 {
+		t.Fatalf("FAILED - expected %d but got %d, %v", 1, out, err)
+	}
+}
+
+func TestCache_GetOrCompute(t *testing.T) {
+	type unitTest struct {
+		name          string
+		setup         func(c *Cache[int])
+		err           error
+		expected      int
+		expectedErr   error
+		expectedCalls int32
+		expectedFound bool
+	}
+
+	failed := errors.New("failed")
+	tests := []unitTest{
+		{name: "Miss", setup: func(c *Cache[int]) {}, expected: 2, expectedCalls: 1, expectedFound: true},
+		{name: "Hit", setup: func(c *Cache[int]) { c.Set("key", 1) }, expected: 1, expectedCalls: 0, expectedFound: true},
+		{name: "Expired", setup: func(c *Cache[int]) { c.Set("key", 1, time.Nanosecond) }, expected: 2, expectedCalls: 1, expectedFound: true},
+		{name: "Error", setup: func(c *Cache[int]) {}, err: failed, expected: 0, expectedErr: failed, expectedCalls: 1, expectedFound: false},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		test.setup(c)
+		time.Sleep(time.Millisecond)
+		var calls atomic.Int32
+		actual, err := c.GetOrCompute("key", func() (int, error) {
+			calls.Add(1)
+			if test.err != nil {
+				return 0, test.err
+			}
+			return 2, nil
+		}, time.Minute)
+		if actual != test.expected || err != test.expectedErr {
+			t.Fatalf("%s FAILED - expected %d, %v but got %d, %v", test.name, test.expected, test.expectedErr, actual, err)
+		}
+		if n := calls.Load(); n != test.expectedCalls {
+			t.Fatalf("%s FAILED - expected %d calls but got %d", test.name, test.expectedCalls, n)
+		}
+		if _, found := c.Get("key"); found != test.expectedFound {
+			t.Fatalf("%s FAILED - expected %t but got %t", test.name, test.expectedFound, found)
+		}
+	}
+
+	c := New[int](time.Hour)
+	if _, err := c.GetOrCompute("key", func() (int, error) { return 1, nil }, time.Minute); err != nil {
+		t.Fatalf("TTL FAILED - expected %v but got %v", nil, err)
+	}
+	if ttl := c.TTLMany("key")["key"]; ttl > time.Minute || ttl < time.Second*59 {
+		t.Fatalf("TTL FAILED - expected about %s but got %s", time.Minute, ttl)
+	}
+}
+
+func TestCache_GetOrComputeDoesNotHoldLock(t *testing.T) {
+	c := New[int](time.Hour)
+	_, err := c.GetOrCompute("key", func() (int, error) {
+		// A loader that uses the cache itself would deadlock if it ran under the cache's lock.
+		c.Set("other", 1)
+		if _, found := c.Get("other"); !found {
+			return 0, errors.New("other was not found")
+		}
+		return 2, nil
+	})
+	if err != nil {
+		t.Fatalf("FAILED - expected %v but got %v", nil, err)
+	}
+}
+
+func TestCache_Once(t *testing.T) {
+	c := New[int](time.Millisecond*10, WithMaxTTL[int](time.Millisecond*20), WithMaxLifetime[int](time.Millisecond*20))
+	var calls atomic.Int32
+	initialize := func() int {
+		calls.Add(1)
+		return 42
+	}
+
+	if value := c.Once("singleton", initialize); value != 42 {
+		t.Fatalf("FAILED - expected %d but got %d", 42, value)
+	}
+	time.Sleep(time.Millisecond * 30)
+	if value := c.Once("singleton", initialize); value != 42 {
+		t.Fatalf("FAILED - expected %d but got %d", 42, value)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("FAILED - expected %d calls but got %d", 1, n)
+	}
+	if value, found := c.Get("singleton"); !found || value != 42 {
+		t.Fatalf("FAILED - expected %d to be stored past the TTL bounds but got %d, %t", 42, value, found)
+	}
+
+	c.Delete("singleton")
+	c.Once("singleton", initialize)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("FAILED - expected %d calls after Delete but got %d", 2, n)
+	}
+}
+
+func TestCache_OnceConcurrent(t *testing.T) {
+	c := New[int](time.Hour)
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value := c.Once("singleton", func() int {
+				calls.Add(1)
+				<-release
+				return 1
+			})
+			if value != 1 {
+				t.Errorf("FAILED - expected %d but got %d", 1, value)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("FAILED - expected %d calls but got %d", 1, n)
+	}
+}
+
+func TestCache_GetOrComputeNoOverwrite(t *testing.T) {
+	c := New[int](time.Hour, WithNoOverwrite[int]())
+	value, err := c.GetOrCompute("key", func() (int, error) {
+		// Another write stores the key while the value is being computed.
+		c.Set("key", 1)
+		return 2, nil
+	})
+	if value != 1 || err != nil {
+		t.Fatalf("FAILED - expected %d, %v but got %d, %v", 1, nil, value, err)
+	}
+
+	value = c.Once("once", func() int {
+		c.Set("once", 1)
+		return 2
+	})
+	if value != 1 {
+		t.Fatalf("Once FAILED - expected %d but got %d", 1, value)
+	}
+	if stored, _ := c.Get("once"); stored != 1 {
+		t.Fatalf("Once FAILED - expected %d to be kept but got %d", 1, stored)
+	}
+}
+
+func TestCache_GetOrComputePanic(t *testing.T) {
+	c := New[int](time.Hour)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	leader := make(chan any)
+	go func() {
+		defer func() {
+			leader <- recover()
+		}()
+		_, _ = c.GetOrCompute("key", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		value, err := c.GetOrCompute("key", func() (int, error) { return 1, nil })
+		if value != 0 {
+			t.Errorf("FAILED - expected %d but got %d", 0, value)
+		}
+		waiter <- err
+	}()
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+
+	if r := <-leader; r != "boom" {
+		t.Fatalf("FAILED - expected the caller of fn to panic with %q but got %v", "boom", r)
+	}
+	if err := <-waiter; !errors.Is(err, ErrLoadPanicked) {
+		t.Fatalf("FAILED - expected %v but got %v", ErrLoadPanicked, err)
+	}
+	if _, found := c.Get("key"); found {
+		t.Fatal("FAILED - a value was stored after fn panicked")
+	}
+	if value, err := c.GetOrCompute("key", func() (int, error) { return 1, nil }); value != 1 || err != nil {
+		t.Fatalf("FAILED - expected %d, %v but got %d, %v", 1, nil, value, err)
+	}
+}
+
+func TestCache_OncePanic(t *testing.T) {
+	c := New[int](time.Hour)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	recovered := make(chan any, 2)
+	call := func(fn func() int) {
+		defer func() {
+			recovered <- recover()
+		}()
+		c.Once("key", fn)
+	}
+
+	go call(func() int {
+		close(started)
+		<-release
+		panic("boom")
+	})
+	<-started
+	go call(func() int { return 1 })
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+
+	for n := 0; n < 2; n++ {
+		if r := <-recovered; r == nil {
+			t.Fatal("FAILED - expected every caller sharing the call to panic")
+		}
+	}
+	if value := c.Once("key", func() int { return 1 }); value != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, value)
+	}
+}
@@ -0,0 +1,92 @@
+package simcache
+
+import "context"
+
+// ctxCheckInterval is how many items the context-aware scans visit between checks of their context.
+const ctxCheckInterval = 256
+
+// ItemsCtx returns a copy of the cache's map that holds type T, like Items, but stops early when ctx is done.
+// If the scan was stopped it returns the items found so far along with ctx.Err(), so a nil error means the
+// map is complete. Expired items are skipped but not deleted.
+func (c *cache[T]) ItemsCtx(ctx context.Context) (map[string]T, error) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	items := make(map[string]T)
+	visited := 0
+	for k, i := range c.items {
+		visited++
+		if visited%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return items, err
+			}
+		}
+		if !c.expired(i) {
+			items[k] = i.value
+		}
+	}
+	return items, nil
+}
+
+// PurgeCtx removes expired items from the cache, like Purge, but stops early when ctx is done.
+// It returns the number of items deleted, along with ctx.Err() if the purge was stopped before it finished.
+// If another purge is running, it waits for that one to finish before starting, or returns 0 and ctx.Err() if ctx is
+// done first.
+func (c *cache[T]) PurgeCtx(ctx context.Context) (int, error) {
+	f, err := c.startPurge(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.endPurge(f)
+	c.lock()
+	defer c.mutex.Unlock()
+
+	count := 0
+	visited := 0
+	for k, i := range c.items {
+		visited++
+		if visited%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+		}
+		if c.removable(i) {
+			delete(c.items, k)
+			c.changed()
+			c.markDirty(k)
+			count++
+		}
+	}
+	if count > 0 {
+		c.snapshot.Store(nil)
+	}
+	c.length.Store(int64(len(c.items)))
+	f.value = count
+	if err != nil {
+		return count, err
+	}
+	c.rebuildFilter()
+	return count, nil
+}
+
+// startPurge waits until no other purge is running and starts a new one, which must be finished with endPurge.
+// It is used by the purges that cannot share another purge's result, so that they never run alongside it.
+// It returns ctx.Err() if ctx is done before the running purge finishes.
+func (c *cache[T]) startPurge(ctx context.Context) (*flight[int], error) {
+	for {
+		f, started := c.beginPurge()
+		if started {
+			return f, nil
+		}
+		done := make(chan struct{})
+		go func() {
+			f.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
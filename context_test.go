@@ -0,0 +1,65 @@
+package simcache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// cancelAfter is a context that is cancelled once Err has been called n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	c.n--
+	if c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestCache_ItemsCtx(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < ctxCheckInterval*10; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+
+	items, err := c.ItemsCtx(context.Background())
+	if err != nil || len(items) != ctxCheckInterval*10 {
+		t.Fatalf("FAILED - expected %d items but got %d, %v", ctxCheckInterval*10, len(items), err)
+	}
+
+	items, err = c.ItemsCtx(&cancelAfter{Context: context.Background(), n: 2})
+	if err != context.Canceled {
+		t.Fatalf("FAILED - expected %v but got %v", context.Canceled, err)
+	}
+	if len(items) != ctxCheckInterval*3-1 {
+		t.Fatalf("FAILED - expected %d items but got %d", ctxCheckInterval*3-1, len(items))
+	}
+}
+
+func TestCache_PurgeCtx(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < ctxCheckInterval*10; i++ {
+		c.Set(strconv.Itoa(i), i, time.Nanosecond)
+	}
+	time.Sleep(time.Nanosecond * 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	count, err := c.PurgeCtx(ctx)
+	if err != context.Canceled {
+		t.Fatalf("FAILED - expected %v but got %v", context.Canceled, err)
+	}
+	if count != ctxCheckInterval-1 {
+		t.Fatalf("FAILED - expected %d but got %d", ctxCheckInterval-1, count)
+	}
+
+	count, err = c.PurgeCtx(context.Background())
+	if err != nil || count != ctxCheckInterval*9+1 {
+		t.Fatalf("FAILED - expected %d but got %d, %v", ctxCheckInterval*9+1, count, err)
+	}
+}
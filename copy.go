@@ -0,0 +1,14 @@
+package simcache
+
+// noCopy makes go vet's copylocks check report copies of the struct that holds it.
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
+
+// checkCopy panics if the cache was created WithCopyCheck and is being used through a copy of itself.
+func (c *cache[T]) checkCopy() {
+	if c.self != nil && c.self != c {
+		panic("simcache: cache used through a copy; share the *Cache instead of copying it")
+	}
+}
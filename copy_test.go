@@ -0,0 +1,50 @@
+package simcache
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// copyCache returns a shallow copy of the cache's internal state, as a caller dereferencing and copying it would make.
+// It copies through reflection so that go vet does not reject the test itself.
+func copyCache[T any](c *Cache[T]) *Cache[T] {
+	copied := reflect.New(reflect.TypeOf(c.cache).Elem())
+	copied.Elem().Set(reflect.ValueOf(c.cache).Elem())
+	return &Cache[T]{cache: copied.Interface().(*cache[T])}
+}
+
+func TestCache_CopyCheck(t *testing.T) {
+	type unitTest struct {
+		name   string
+		c      *Cache[int]
+		panics bool
+	}
+
+	tests := []unitTest{
+		{name: "Without Check", c: New[int](time.Hour), panics: false},
+		{name: "With Check", c: New[int](time.Hour, WithCopyCheck[int]()), panics: true},
+	}
+
+	for _, test := range tests {
+		test.c.Set("one", 1)
+		if _, found := test.c.Get("one"); !found {
+			t.Fatalf(`%s FAILED - "one" was not found in the original`, test.name)
+		}
+
+		copied := copyCache(test.c)
+		func() {
+			defer func() {
+				r := recover()
+				if (r != nil) != test.panics {
+					t.Fatalf("%s FAILED - expected a panic: %t but got %v", test.name, test.panics, r)
+				}
+				if r != nil && !strings.Contains(r.(string), "copy") {
+					t.Fatalf("%s FAILED - unexpected panic %v", test.name, r)
+				}
+			}()
+			copied.Get("one")
+		}()
+	}
+}
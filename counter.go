@@ -0,0 +1,50 @@
+package simcache
+
+import "time"
+
+// IncrementWithCap adds delta to the counter in the cache for a given key, but only if the result would not exceed
+// limit. It returns the counter's value afterwards and whether the increment was allowed.
+// A key that is missing or expired starts at zero, with an expiration set from the given TTL, or the default TTL if
+// none is given, so the TTL sets the window of a fixed-window rate limiter. Later increments keep that expiration.
+func IncrementWithCap(c *Cache[int64], key string, delta, limit int64, ttl ...time.Duration) (int64, bool) {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	value := counter(c, key)
+	if value+delta > limit {
+		return value, false
+	}
+	return increment(c, key, delta, ttl...), true
+}
+
+// counter returns the live value of the counter for a given key, or zero if it is missing or expired.
+// The caller must hold the lock.
+func counter(c *Cache[int64], key string) int64 {
+	i, found := c.items[key]
+	if !found || c.expired(i) {
+		return 0
+	}
+	return i.value
+}
+
+// increment adds delta to the counter for a given key and returns its new value. A key that is missing or expired
+// starts at zero with an expiration set from the given TTL, and later increments keep that expiration.
+// The caller must hold the write lock.
+func increment(c *Cache[int64], key string, delta int64, ttl ...time.Duration) int64 {
+	i, found := c.items[key]
+	if !found || c.expired(i) {
+		key = c.intern(key)
+		var clamped bool
+		i, clamped = c.newItem(delta, ttl...)
+		c.store(key, i, clamped)
+		return delta
+	}
+
+	i.value += delta
+	i.written = time.Now().UTC()
+	c.items[key] = i
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	return i.value
+}
@@ -0,0 +1,111 @@
+package simcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIncrementWithCap(t *testing.T) {
+	c := New[int64](time.Hour)
+
+	type unitTest struct {
+		name     string
+		delta    int64
+		expected int64
+		allowed  bool
+	}
+
+	tests := []unitTest{
+		{
+			name:     "First",
+			delta:    2,
+			expected: 2,
+			allowed:  true,
+		},
+		{
+			name:     "Up To Cap",
+			delta:    3,
+			expected: 5,
+			allowed:  true,
+		},
+		{
+			name:     "Over Cap",
+			delta:    1,
+			expected: 5,
+			allowed:  false,
+		},
+	}
+
+	for _, test := range tests {
+		value, allowed := IncrementWithCap(c, "requests", test.delta, 5, time.Minute)
+		if value != test.expected || allowed != test.allowed {
+			t.Fatalf("%s FAILED - expected %d, %t but got %d, %t", test.name, test.expected, test.allowed, value, allowed)
+		}
+	}
+	if ttl := time.Until(c.items["requests"].expiration); ttl > time.Minute {
+		t.Fatalf("FAILED - expected the window to be one minute but got %s", ttl)
+	}
+
+	// A new window starts from zero once the previous one expires.
+	IncrementWithCap(c, "window", 5, 5, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	if value, allowed := IncrementWithCap(c, "window", 1, 5); value != 1 || !allowed {
+		t.Fatalf("FAILED - expected %d, %t but got %d, %t", 1, true, value, allowed)
+	}
+
+	if value, allowed := IncrementWithCap(c, "too big", 6, 5); value != 0 || allowed {
+		t.Fatalf("FAILED - expected %d, %t but got %d, %t", 0, false, value, allowed)
+	}
+	if _, found := c.Get("too big"); found {
+		t.Fatal(`FAILED - "too big" was created`)
+	}
+}
+
+func TestIncrementWithCap_Concurrent(t *testing.T) {
+	c := New[int64](time.Hour)
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := IncrementWithCap(c, "requests", 1, 10); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := allowed.Load(); n != 10 {
+		t.Fatalf("FAILED - expected %d but got %d", 10, n)
+	}
+}
+
+func TestCache_GetAndReset(t *testing.T) {
+	c := New[int64](time.Hour)
+	IncrementWithCap(c, "requests", 3, 10, time.Minute)
+	expiration := c.items["requests"].expiration
+
+	if value, found := c.GetAndReset("requests"); !found || value != 3 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 3, value, found)
+	}
+	if value, found := c.Get("requests"); !found || value != 0 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 0, value, found)
+	}
+	if c.items["requests"].expiration != expiration {
+		t.Fatal("FAILED - expected the expiration to be kept")
+	}
+	if value, _ := IncrementWithCap(c, "requests", 1, 10); value != 1 {
+		t.Fatalf("FAILED - expected %d but got %d", 1, value)
+	}
+
+	c.Set("expired", 1, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	for _, k := range []string{"expired", "missing"} {
+		if _, found := c.GetAndReset(k); found {
+			t.Fatalf("FAILED - %q was found", k)
+		}
+	}
+}
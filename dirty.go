@@ -0,0 +1,35 @@
+package simcache
+
+// DirtyKeys returns the keys written, deleted or given a new expiration since the cache was created, ClearDirty was last
+// called or SaveJSONL last succeeded, when the cache was created WithDirtyTracking. Otherwise it returns nil.
+func (c *cache[T]) DirtyKeys() []string {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	if c.dirty == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(c.dirty))
+	for k := range c.dirty {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// ClearDirty forgets the keys returned by DirtyKeys, so that only keys written or deleted after it are returned.
+func (c *cache[T]) ClearDirty() {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	if c.dirty != nil {
+		clear(c.dirty)
+	}
+}
+
+// markDirty records that the key was written or deleted, when the cache was created WithDirtyTracking.
+// The caller must hold the write lock.
+func (c *cache[T]) markDirty(key string) {
+	if c.dirty != nil {
+		c.dirty[key] = struct{}{}
+	}
+}
@@ -0,0 +1,87 @@
+package simcache
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCache_DirtyKeys(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("one", 1)
+	if keys := c.DirtyKeys(); keys != nil {
+		t.Fatalf("FAILED - expected no dirty keys without WithDirtyTracking but got %v", keys)
+	}
+
+	c = New[int](time.Hour, WithDirtyTracking[int](), WithChangeDetection(func(old, value int) bool {
+		return old == value
+	}))
+	c.Set("one", 1)
+	c.Add("two", 2)
+	c.Set("three", 3)
+	assertDirtyKeys(t, c, "one", "three", "two")
+
+	c.ClearDirty()
+	assertDirtyKeys(t, c)
+
+	c.Set("one", 1)
+	c.Get("two")
+	assertDirtyKeys(t, c)
+
+	c.Set("one", 10)
+	c.Delete("two")
+	assertDirtyKeys(t, c, "one", "two")
+}
+
+func assertDirtyKeys(t *testing.T, c *Cache[int], expected ...string) {
+	t.Helper()
+	keys := c.DirtyKeys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, expected) {
+		t.Fatalf("FAILED - expected %v but got %v", expected, keys)
+	}
+}
+
+func TestCache_DirtyKeysRemoved(t *testing.T) {
+	type unitTest struct {
+		name     string
+		op       func(c *Cache[int])
+		expected []string
+	}
+
+	tests := []unitTest{
+		{name: "Purge", op: func(c *Cache[int]) { c.Purge() }, expected: []string{"expired"}},
+		{name: "PurgeAtomic", op: func(c *Cache[int]) { c.PurgeAtomic() }, expected: []string{"expired"}},
+		{name: "PurgeCtx", op: func(c *Cache[int]) { _, _ = c.PurgeCtx(context.Background()) }, expected: []string{"expired"}},
+		{name: "ExpireAll", op: func(c *Cache[int]) { c.ExpireAll() }, expected: []string{"live"}},
+		{
+			name: "ApplyTTL",
+			op: func(c *Cache[int]) {
+				c.ApplyTTL(func(key string) (time.Duration, bool) { return time.Minute, true })
+			},
+			expected: []string{"live"},
+		},
+		{name: "GetAndTouch", op: func(c *Cache[int]) { c.GetAndTouch("live") }, expected: []string{"live"}},
+		{name: "SaveJSONL", op: func(c *Cache[int]) { _ = c.SaveJSONL(io.Discard) }, expected: nil},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour, WithDirtyTracking[int]())
+		c.Set("live", 1)
+		c.Set("expired", 2, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		c.ClearDirty()
+		if test.name == "SaveJSONL" {
+			c.Set("live", 3)
+		}
+
+		test.op(c)
+		keys := c.DirtyKeys()
+		slices.Sort(keys)
+		if !slices.Equal(keys, test.expected) {
+			t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, keys)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package simcache
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+// bloomFilter is a bloom filter of keys that can be read without locking.
+// It can report that a key may be present when it is not, but never that a key is absent when it was added.
+type bloomFilter struct {
+	bits   []atomic.Uint64
+	hashes uint64
+	seed   maphash.Seed
+}
+
+// The false positive rates a miss filter can be created with. Lower rates take more memory: 1e-6 takes about 29 bits
+// per key.
+const (
+	minFilterRate = 1e-6
+	maxFilterRate = 0.5
+)
+
+// clampFilterRate limits a false positive rate to the range a miss filter can be created with. Rates of 0 or less,
+// and NaN, become minFilterRate.
+func clampFilterRate(fpRate float64) float64 {
+	if !(fpRate >= minFilterRate) {
+		return minFilterRate
+	}
+	return min(fpRate, maxFilterRate)
+}
+
+// newBloomFilter creates a bloom filter sized to hold the expected number of items with the given false
+// positive rate.
+func newBloomFilter(expectedItems int, fpRate float64) *bloomFilter {
+	n := float64(max(expectedItems, 1))
+	m := math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+	return &bloomFilter{
+		bits:   make([]atomic.Uint64, (int(m)+63)/64),
+		hashes: uint64(k),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+func (f *bloomFilter) add(key string) {
+	h := maphash.String(f.seed, key)
+	for i := uint64(0); i < f.hashes; i++ {
+		word, mask := f.position(h, i)
+		f.bits[word].Or(mask)
+	}
+}
+
+func (f *bloomFilter) mayContain(key string) bool {
+	h := maphash.String(f.seed, key)
+	for i := uint64(0); i < f.hashes; i++ {
+		word, mask := f.position(h, i)
+		if f.bits[word].Load()&mask == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// position returns the word and bit mask of the i'th bit for a key's hash, using double hashing to derive each
+// bit from the two halves of the hash.
+func (f *bloomFilter) position(h, i uint64) (int, uint64) {
+	h1, h2 := h>>32, h<<32>>32|1
+	// Map the combined hash onto the filter's bits without a division.
+	bit, _ := bits.Mul64(h1*0x9e3779b97f4a7c15+i*h2*0xbf58476d1ce4e5b9, uint64(len(f.bits))*64)
+	return int(bit / 64), 1 << (bit % 64)
+}
+
+// mayContain returns false if the key is definitely not in the cache, without taking the lock. It always returns
+// true when the cache was not created WithMissFilter.
+func (c *cache[T]) mayContain(key string) bool {
+	f := c.filter.Load()
+	return f == nil || f.mayContain(key)
+}
+
+// rebuildFilter replaces the miss filter with one holding only the keys currently in the cache, dropping keys that
+// have since been deleted. The caller must hold the lock.
+func (c *cache[T]) rebuildFilter() {
+	if c.filter.Load() == nil {
+		return
+	}
+	f := newBloomFilter(max(c.filterItems, len(c.items)), c.filterRate)
+	for k := range c.items {
+		f.add(k)
+	}
+	c.filter.Store(f)
+}
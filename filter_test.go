@@ -0,0 +1,97 @@
+package simcache
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCache_MissFilter(t *testing.T) {
+	c := New[int](time.Hour, WithMissFilter[int](1000, 0.01))
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	if !c.Add("added", 1) {
+		t.Fatal(`FAILED - "added" was not added`)
+	}
+
+	// No false negatives.
+	for i := 0; i < 1000; i++ {
+		if value, found := c.Get(strconv.Itoa(i)); !found || value != i {
+			t.Fatalf("FAILED - expected %d but got %d, %t", i, value, found)
+		}
+	}
+	if _, found := c.Get("added"); !found {
+		t.Fatal(`FAILED - "added" was not found`)
+	}
+	counters := New[int64](time.Hour, WithMissFilter[int64](1, 0.01))
+	IncrementWithCap(counters, "counter", 1, 1)
+	if _, found := counters.Get("counter"); !found {
+		t.Fatal(`FAILED - "counter" was not found`)
+	}
+
+	falsePositives := 0
+	for i := 1000; i < 11000; i++ {
+		if c.mayContain(strconv.Itoa(i)) {
+			falsePositives++
+		}
+		if _, found := c.Get(strconv.Itoa(i)); found {
+			t.Fatalf("FAILED - %d was found", i)
+		}
+	}
+	if rate := float64(falsePositives) / 10000; rate > 0.03 {
+		t.Fatalf("FAILED - expected a false positive rate near %f but got %f", 0.01, rate)
+	}
+
+	// Purge rebuilds the filter without deleted keys, while keeping the rest.
+	for i := 0; i < 500; i++ {
+		c.Delete(strconv.Itoa(i))
+	}
+	c.Purge()
+	stale := 0
+	for i := 0; i < 500; i++ {
+		if c.mayContain(strconv.Itoa(i)) {
+			stale++
+		}
+	}
+	if stale > 50 {
+		t.Fatalf("FAILED - expected deleted keys to leave the filter but %d remain", stale)
+	}
+	for i := 500; i < 1000; i++ {
+		if _, found := c.Get(strconv.Itoa(i)); !found {
+			t.Fatalf("FAILED - %d was not found after rebuilding the filter", i)
+		}
+	}
+}
+
+func TestCache_MissFilterRate(t *testing.T) {
+	type unitTest struct {
+		name     string
+		fpRate   float64
+		expected float64
+	}
+
+	tests := []unitTest{
+		{name: "Zero", fpRate: 0, expected: minFilterRate},
+		{name: "Negative", fpRate: -0.5, expected: minFilterRate},
+		{name: "NaN", fpRate: math.NaN(), expected: minFilterRate},
+		{name: "One", fpRate: 1, expected: maxFilterRate},
+		{name: "Above One", fpRate: 2, expected: maxFilterRate},
+		{name: "Valid", fpRate: 0.01, expected: 0.01},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour, WithMissFilter[int](100, test.fpRate))
+		if c.filterRate != test.expected {
+			t.Fatalf("%s FAILED - expected %f but got %f", test.name, test.expected, c.filterRate)
+		}
+		c.Set("one", 1)
+		if _, found := c.Get("one"); !found {
+			t.Fatalf(`%s FAILED - "one" was not found`, test.name)
+		}
+		if _, found := c.Get("two"); found {
+			t.Fatalf(`%s FAILED - "two" was found`, test.name)
+		}
+	}
+}
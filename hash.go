@@ -0,0 +1,41 @@
+package simcache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+)
+
+// ContentHash returns a hash of the live keys and values in the cache. It is the same between calls for as long as
+// the contents are unchanged, regardless of expirations, so it can be used to tell whether the cache has changed.
+// Values are hashed with the function set WithValueHasher. Without one, they are formatted with fmt's %v verb,
+// which suits values made of basic types, but hashes pointers by their address rather than what they point to.
+func (c *cache[T]) ContentHash() uint64 {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	keys := make([]string, 0, len(c.items))
+	for k, i := range c.items {
+		if !c.expired(i) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, k := range keys {
+		_, _ = h.Write([]byte(k))
+		_, _ = h.Write([]byte{0})
+		value := c.items[k].value
+		if c.hasher != nil {
+			binary.LittleEndian.PutUint64(buf[:], c.hasher(value))
+			_, _ = h.Write(buf[:])
+		} else {
+			_, _ = fmt.Fprintf(h, "%v", value)
+		}
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
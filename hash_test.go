@@ -0,0 +1,56 @@
+package simcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_ContentHash(t *testing.T) {
+	a := New[int](time.Hour)
+	b := New[int](time.Minute)
+	for _, c := range []*Cache[int]{a, b} {
+		c.Set("one", 1)
+		c.Set("two", 2)
+	}
+	b.Set("expired", 3, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	hash := a.ContentHash()
+	if a.ContentHash() != hash {
+		t.Fatal("FAILED - expected the hash to be stable")
+	}
+	if b.ContentHash() != hash {
+		t.Fatal("FAILED - expected caches with the same contents to have the same hash")
+	}
+
+	a.Set("two", 3)
+	if a.ContentHash() == hash {
+		t.Fatal("FAILED - expected changing a value to change the hash")
+	}
+	a.Set("two", 2)
+	if a.ContentHash() != hash {
+		t.Fatal("FAILED - expected restoring a value to restore the hash")
+	}
+	a.Delete("two")
+	if a.ContentHash() == hash {
+		t.Fatal("FAILED - expected deleting a key to change the hash")
+	}
+}
+
+func TestCache_ContentHashWithHasher(t *testing.T) {
+	type user struct {
+		name *string
+	}
+	hasher := func(u user) uint64 {
+		return uint64(len(*u.name))
+	}
+	a := New[user](time.Hour, WithValueHasher(hasher))
+	b := New[user](time.Hour, WithValueHasher(hasher))
+	first, second := "will", "will"
+	a.Set("user", user{name: &first})
+	b.Set("user", user{name: &second})
+
+	if a.ContentHash() != b.ContentHash() {
+		t.Fatal("FAILED - expected the hasher to be used for values")
+	}
+}
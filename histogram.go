@@ -0,0 +1,100 @@
+package simcache
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// defaultLoadBuckets are the upper bounds of the load latency histogram's buckets, doubling from 1µs to about 33s so
+// that each bucket is within a factor of two of the latencies it holds.
+var defaultLoadBuckets = doublingBuckets(time.Microsecond, 26)
+
+// lockWaitBuckets are the upper bounds of the lock wait histogram's buckets, doubling from 100ns to about 13s, since
+// most waits for an uncontended lock are far shorter than a load.
+var lockWaitBuckets = doublingBuckets(time.Nanosecond*100, 28)
+
+// doublingBuckets returns count upper bounds, starting at first and doubling each time.
+func doublingBuckets(first time.Duration, count int) []time.Duration {
+	bounds := make([]time.Duration, count)
+	for n := range bounds {
+		bounds[n] = first << n
+	}
+	return bounds
+}
+
+// LoadBucket is a bucket of the load latency histogram returned by Stats.
+type LoadBucket struct {
+	// UpperBound is the longest load counted in the bucket. The last bucket counts every load longer than the
+	// bucket before it, and its UpperBound is the maximum time.Duration.
+	UpperBound time.Duration
+	// Count is the number of loads in the bucket.
+	Count uint64
+}
+
+// histogram counts durations in buckets with fixed upper bounds. It is safe for concurrent use.
+type histogram struct {
+	bounds []time.Duration
+	// counts has one more entry than bounds, for durations longer than the last bound.
+	counts []atomic.Uint64
+	max    atomic.Int64
+}
+
+// newHistogram returns a histogram with buckets for the given upper bounds, which are sorted and deduplicated.
+func newHistogram(bounds []time.Duration) *histogram {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	return &histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+func (h *histogram) record(d time.Duration) {
+	n, _ := slices.BinarySearch(h.bounds, d)
+	h.counts[n].Add(1)
+	storeMax(&h.max, int64(d))
+}
+
+// buckets returns a snapshot of the histogram's buckets.
+func (h *histogram) buckets() []LoadBucket {
+	buckets := make([]LoadBucket, len(h.counts))
+	for n := range h.counts {
+		bound := time.Duration(math.MaxInt64)
+		if n < len(h.bounds) {
+			bound = h.bounds[n]
+		}
+		buckets[n] = LoadBucket{UpperBound: bound, Count: h.counts[n].Load()}
+	}
+	return buckets
+}
+
+// percentile estimates the q-th quantile, between 0 and 1, of the durations counted in buckets as the upper bound of
+// the bucket it falls in. The estimate is never more than limit, the longest duration recorded.
+func percentile(buckets []LoadBucket, q float64, limit time.Duration) time.Duration {
+	var total uint64
+	for _, b := range buckets {
+		total += b.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for _, b := range buckets {
+		seen += b.Count
+		if seen >= rank {
+			return min(b.UpperBound, limit)
+		}
+	}
+	return limit
+}
+
+// storeMax stores d in v if it is greater than the value already there.
+func storeMax(v *atomic.Int64, d int64) {
+	for {
+		current := v.Load()
+		if d <= current || v.CompareAndSwap(current, d) {
+			return
+		}
+	}
+}
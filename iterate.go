@@ -0,0 +1,31 @@
+package simcache
+
+import "iter"
+
+// All returns an iterator over the cache's unexpired key-value pairs, for use with range.
+//
+// The keys are copied under the read lock when iteration starts, and each value is then read with its own short read
+// lock just before it is yielded, so the cache is never locked while the loop body runs. Iteration is safe while
+// other goroutines write to the cache:
+//   - it never panics and never yields a key twice;
+//   - keys added after iteration started are not yielded;
+//   - keys deleted or expired before they are reached are skipped;
+//   - each value is the one stored when its key was reached, not when iteration started.
+//
+// The pairs are therefore not a consistent snapshot of the cache at any one moment; use Items or PurgeAtomic for that.
+func (c *cache[T]) All() iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		for _, key := range c.Keys() {
+			c.rlock()
+			i, found := c.items[key]
+			live := found && !c.expired(i)
+			c.mutex.RUnlock()
+			if !live {
+				continue
+			}
+			if !yield(key, i.value) {
+				return
+			}
+		}
+	}
+}
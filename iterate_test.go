@@ -0,0 +1,120 @@
+package simcache
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_All(t *testing.T) {
+	type unitTest struct {
+		name     string
+		setup    func(c *Cache[int])
+		expected map[string]int
+	}
+
+	tests := []unitTest{
+		{name: "Empty", setup: func(c *Cache[int]) {}, expected: map[string]int{}},
+		{
+			name: "Live Items",
+			setup: func(c *Cache[int]) {
+				c.Set("one", 1)
+				c.Set("two", 2)
+			},
+			expected: map[string]int{"one": 1, "two": 2},
+		},
+		{
+			name: "Expired Items",
+			setup: func(c *Cache[int]) {
+				c.Set("one", 1)
+				c.Set("two", 2, time.Nanosecond)
+			},
+			expected: map[string]int{"one": 1},
+		},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		test.setup(c)
+		time.Sleep(time.Millisecond)
+		actual := make(map[string]int)
+		for k, v := range c.All() {
+			actual[k] = v
+		}
+		if len(actual) != len(test.expected) {
+			t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, actual)
+		}
+		for k, v := range test.expected {
+			if actual[k] != v {
+				t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, actual)
+			}
+		}
+	}
+
+	c := New[int](time.Hour)
+	c.Set("one", 1)
+	c.Set("two", 2)
+	count := 0
+	for range c.All() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Fatalf("Break FAILED - expected %d but got %d", 1, count)
+	}
+}
+
+func TestCache_AllConcurrentWrites(t *testing.T) {
+	const keys = 1000
+	c := New[int](time.Hour)
+	for n := 0; n < keys; n++ {
+		c.Set(strconv.Itoa(n), n)
+	}
+
+	// The writer keeps each key's value equal to the key modulo keys, so values can be checked against their keys.
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for round := 1; !stop.Load(); round++ {
+			n := round % keys
+			key := strconv.Itoa(n)
+			switch round % 3 {
+			case 0:
+				c.Delete(key)
+			case 1:
+				c.Set(key, n+keys*round)
+			case 2:
+				c.Set("new-"+strconv.Itoa(round), n)
+			}
+			runtime.Gosched()
+		}
+	}()
+
+	seen := make(map[string]bool)
+	for k, v := range c.All() {
+		if seen[k] {
+			t.Fatalf("All FAILED - key %q was yielded twice", k)
+		}
+		seen[k] = true
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			t.Fatalf("All FAILED - key %q was added after iteration started", k)
+		}
+		if v%keys != n {
+			t.Fatalf("All FAILED - expected a value for key %q but got %d", k, v)
+		}
+		// Let the writer run between every step of the iteration.
+		runtime.Gosched()
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if len(seen) == 0 {
+		t.Fatalf("All FAILED - expected keys to be yielded")
+	}
+}
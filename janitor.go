@@ -0,0 +1,64 @@
+package simcache
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// janitor periodically deletes expired items from a cache on a goroutine of its own.
+type janitor struct {
+	interval time.Duration
+	done     chan struct{}
+	once     sync.Once
+}
+
+// startJanitor starts the janitor of a cache created WithJanitor. The goroutine only refers to the inner cache, so
+// the Cache can still be garbage collected while it runs, and a finalizer on it stops the janitor when it is.
+func startJanitor[T any](c *Cache[T]) {
+	if c.janitor == nil {
+		return
+	}
+	c.janitor.done = make(chan struct{})
+	go c.cache.runJanitor()
+	runtime.SetFinalizer(c, func(c *Cache[T]) {
+		c.Close()
+	})
+}
+
+// runJanitor deletes expired items every interval until the cache is closed. A tick is skipped if a Purge is
+// already running.
+func (c *cache[T]) runJanitor() {
+	ticker := time.NewTicker(c.janitor.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if f, started := c.beginPurge(); started {
+				f.value = c.sweep()
+				c.endPurge(f)
+			}
+		case <-c.janitor.done:
+			return
+		}
+	}
+}
+
+// Close stops the janitor started WithJanitor. It can be called more than once, and does nothing for a cache without
+// a janitor. The cache can still be used after it is closed, but expired items are no longer deleted in the background.
+func (c *cache[T]) Close() {
+	if c.janitor == nil {
+		return
+	}
+	c.janitor.once.Do(func() {
+		close(c.janitor.done)
+	})
+}
+
+// janitorInterval returns how often the janitor runs, or 0 if the cache has none.
+func (c *cache[T]) janitorInterval() time.Duration {
+	if c.janitor == nil {
+		return 0
+	}
+	return c.janitor.interval
+}
@@ -0,0 +1,79 @@
+package simcache
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCache_WithJanitor(t *testing.T) {
+	type unitTest struct {
+		name     string
+		interval time.Duration
+		expected int
+	}
+
+	tests := []unitTest{
+		{name: "Janitor", interval: time.Millisecond * 5, expected: 0},
+		{name: "Zero Interval", interval: 0, expected: 1},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Millisecond, WithJanitor[int](test.interval))
+		c.Set("one", 1)
+		time.Sleep(time.Millisecond * 50)
+
+		c.rlock()
+		got := len(c.items)
+		c.mutex.RUnlock()
+		if got != test.expected {
+			t.Fatalf("%s FAILED - expected %d items but got %d", test.name, test.expected, got)
+		}
+		if c.Config().Janitor != test.interval {
+			t.Fatalf("%s FAILED - expected interval %s but got %s", test.name, test.interval, c.Config().Janitor)
+		}
+		c.Close()
+	}
+}
+
+func TestCache_Close(t *testing.T) {
+	c := New[int](time.Millisecond, WithJanitor[int](time.Millisecond*5))
+	c.Close()
+	c.Close()
+
+	select {
+	case <-c.janitor.done:
+	default:
+		t.Fatalf("Close FAILED - expected the janitor to be stopped")
+	}
+
+	// Once closed, expired items are no longer deleted in the background.
+	c.Set("one", 1)
+	time.Sleep(time.Millisecond * 50)
+	c.rlock()
+	got := len(c.items)
+	c.mutex.RUnlock()
+	if got != 1 {
+		t.Fatalf("Close FAILED - expected 1 item but got %d", got)
+	}
+
+	New[int](time.Minute).Close()
+}
+
+func TestCache_JanitorStoppedWhenCollected(t *testing.T) {
+	j := func() *janitor {
+		c := New[int](time.Minute, WithJanitor[int](time.Millisecond))
+		return c.janitor
+	}()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		runtime.GC()
+		select {
+		case <-j.done:
+			return
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+	t.Fatalf("Janitor FAILED - expected the janitor to stop once the cache was collected")
+}
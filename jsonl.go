@@ -0,0 +1,81 @@
+package simcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// jsonlEntry is a line written by SaveJSONL and read by LoadJSONL.
+type jsonlEntry[T any] struct {
+	Key       string    `json:"key"`
+	Value     T         `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// SaveJSONL writes every live item in the cache to w as JSON Lines, one object per line holding its key, value and
+// expiration. The items are copied under the read lock and written to w after it is released, so a slow writer does
+// not block the cache. When the cache was created WithDirtyTracking, the keys returned by DirtyKeys are cleared when
+// the items are copied, and restored if writing them fails. Keys written during the save are kept.
+func (c *cache[T]) SaveJSONL(w io.Writer) error {
+	var saved map[string]struct{}
+	if c.dirty != nil {
+		c.lock()
+		saved = c.dirty
+		c.dirty = make(map[string]struct{})
+		c.mutex.Unlock()
+	}
+	c.rlock()
+	entries := make([]jsonlEntry[T], 0, len(c.items))
+	for k, i := range c.items {
+		if !c.expired(i) {
+			entries = append(entries, jsonlEntry[T]{Key: k, Value: i.value, ExpiresAt: i.expiration})
+		}
+	}
+	c.mutex.RUnlock()
+
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			if saved != nil {
+				c.lock()
+				for k := range saved {
+					c.dirty[k] = struct{}{}
+				}
+				c.mutex.Unlock()
+			}
+			return fmt.Errorf("simcache: saving %q: %w", e.Key, err)
+		}
+	}
+	return nil
+}
+
+// LoadJSONL reads items written by SaveJSONL from r and stores them in the cache, one line at a time, keeping their
+// expiration. Items that have already expired, and values rejected by the cache's options, are skipped.
+// If a line cannot be read, the items before it are kept and an error naming the line is returned.
+func (c *cache[T]) LoadJSONL(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var e jsonlEntry[T]
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("simcache: loading line %d: %w", line, err)
+		}
+		now := time.Now().UTC()
+		if !now.Before(e.ExpiresAt) || c.rejects(e.Value) {
+			continue
+		}
+
+		i := item[T]{value: e.Value, created: now, written: now}
+		var clamped bool
+		i.expiration, clamped = c.clampExpiration(now, now, e.ExpiresAt.UTC())
+		key := c.intern(e.Key)
+		c.lock()
+		c.store(key, i, clamped)
+		c.mutex.Unlock()
+	}
+}
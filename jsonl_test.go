@@ -0,0 +1,146 @@
+package simcache
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_JSONL(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int
+	}
+
+	c := New[user](time.Hour)
+	c.Set("ada", user{Name: "Ada", Age: 36})
+	c.Set("alan", user{Name: "Alan", Age: 41}, time.Minute)
+	c.Set("expired", user{Name: "Expired"}, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	var buf bytes.Buffer
+	if err := c.SaveJSONL(&buf); err != nil {
+		t.Fatalf("FAILED - unexpected error %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Fatalf("FAILED - expected %d lines but got %d: %s", 2, lines, buf.String())
+	}
+
+	loaded := New[user](time.Hour)
+	if err := loaded.LoadJSONL(&buf); err != nil {
+		t.Fatalf("FAILED - unexpected error %v", err)
+	}
+	if items := loaded.Items(); len(items) != 2 || items["ada"] != c.items["ada"].value || items["alan"] != c.items["alan"].value {
+		t.Fatalf("FAILED - unexpected items %v", items)
+	}
+	if !loaded.items["alan"].expiration.Equal(c.items["alan"].expiration) {
+		t.Fatalf("FAILED - expected %s but got %s", c.items["alan"].expiration, loaded.items["alan"].expiration)
+	}
+}
+
+func TestCache_LoadJSONL(t *testing.T) {
+	type unitTest struct {
+		name   string
+		input  string
+		err    string
+		length int
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+	tests := []unitTest{
+		{
+			name:   "Empty",
+			input:  "",
+			length: 0,
+		},
+		{
+			name:   "Drops Expired",
+			input:  `{"key":"one","value":1,"expiresAt":"` + future + `"}` + "\n" + `{"key":"two","value":2,"expiresAt":"` + past + `"}`,
+			length: 1,
+		},
+		{
+			name:   "Bad Line",
+			input:  `{"key":"one","value":1,"expiresAt":"` + future + `"}` + "\n" + `{"key":"two","value":"two"}`,
+			err:    "line 2",
+			length: 1,
+		},
+	}
+
+	for _, test := range tests {
+		c := New[int](time.Hour)
+		err := c.LoadJSONL(strings.NewReader(test.input))
+		if (err != nil) != (test.err != "") || err != nil && !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%s FAILED - expected an error containing %q but got %v", test.name, test.err, err)
+		}
+		if length := c.Len(); length != test.length {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, test.length, length)
+		}
+	}
+}
+
+// blockingWriter blocks every write until release is closed, reporting the first write on started.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	return len(p), nil
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCache_SaveJSONLBlocked(t *testing.T) {
+	c := New[int](time.Hour, WithDirtyTracking[int]())
+	c.Set("one", 1)
+
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	errs := make(chan error, 1)
+	go func() { errs <- c.SaveJSONL(w) }()
+	<-w.started
+
+	done := make(chan struct{})
+	go func() {
+		c.Set("two", 2)
+		c.Get("one")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("FAILED - expected Set and Get not to wait for the writer")
+	}
+
+	close(w.release)
+	if err := <-errs; err != nil {
+		t.Fatalf("FAILED - unexpected error %v", err)
+	}
+	if keys := c.DirtyKeys(); !slices.Equal(keys, []string{"two"}) {
+		t.Fatalf("FAILED - expected %v but got %v", []string{"two"}, keys)
+	}
+}
+
+func TestCache_SaveJSONLFailed(t *testing.T) {
+	c := New[int](time.Hour, WithDirtyTracking[int]())
+	c.Set("one", 1)
+
+	if err := c.SaveJSONL(failingWriter{}); err == nil {
+		t.Fatal("FAILED - expected an error")
+	}
+	if keys := c.DirtyKeys(); !slices.Equal(keys, []string{"one"}) {
+		t.Fatalf("FAILED - expected %v but got %v", []string{"one"}, keys)
+	}
+}
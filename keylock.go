@@ -0,0 +1,97 @@
+package simcache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrKeyBusy is returned by WithLock when the key's lock could not be acquired before the timeout.
+var ErrKeyBusy = errors.New("simcache: key busy")
+
+// WithLock runs fn while holding a lock on a single key, without holding the cache's lock, so fn can span more than
+// one cache call. fn is given the key's live value and whether it was found. If fn returns store as true, the
+// value it returns is written to the cache with the default TTL before the key's lock is released.
+// It returns ErrKeyBusy if the key's lock could not be acquired within timeout, or the error returned by fn, in which
+// case nothing is stored. If the cache rejects the value, it returns the error TrySet would.
+// The lock only excludes other calls to WithLock for the same key; other methods do not take it.
+func (c *cache[T]) WithLock(key string, timeout time.Duration, fn func(v T, found bool) (newV T, store bool, err error)) error {
+	if !c.keyLocks.lock(key, timeout) {
+		return ErrKeyBusy
+	}
+	defer c.keyLocks.unlock(key)
+
+	value, found := c.Get(key)
+	newValue, store, err := fn(value, found)
+	if err != nil {
+		return err
+	}
+	if store {
+		return c.set(key, newValue, c.strictTTL, 0)
+	}
+	return nil
+}
+
+// keyLocks is a table of locks for individual keys. A key's lock is removed from the table once nothing holds or
+// waits for it.
+type keyLocks struct {
+	mutex sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	// held has room for one value, which is sent while the lock is held.
+	held chan struct{}
+	refs int
+}
+
+// lock acquires the lock for a given key, returning false if it was not acquired within timeout.
+func (t *keyLocks) lock(key string, timeout time.Duration) bool {
+	t.mutex.Lock()
+	if t.locks == nil {
+		t.locks = make(map[string]*keyLock)
+	}
+	l, found := t.locks[key]
+	if !found {
+		l = &keyLock{held: make(chan struct{}, 1)}
+		t.locks[key] = l
+	}
+	l.refs++
+	t.mutex.Unlock()
+
+	// Try a free lock first, so that it is acquired even when the timer below has already fired: a select with both
+	// cases ready picks one at random.
+	select {
+	case l.held <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case l.held <- struct{}{}:
+		return true
+	case <-timer.C:
+		t.release(key, l)
+		return false
+	}
+}
+
+// unlock releases the lock for a given key.
+func (t *keyLocks) unlock(key string) {
+	t.mutex.Lock()
+	l := t.locks[key]
+	t.mutex.Unlock()
+	<-l.held
+	t.release(key, l)
+}
+
+func (t *keyLocks) release(key string, l *keyLock) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(t.locks, key)
+	}
+}
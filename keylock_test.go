@@ -0,0 +1,148 @@
+package simcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_WithLock(t *testing.T) {
+	c := New[int](time.Hour)
+
+	// Read-modify-write cycles on the same key that yield in the middle must not lose updates.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.WithLock("counter", time.Second, func(v int, found bool) (int, bool, error) {
+				time.Sleep(time.Millisecond)
+				return v + 1, true, nil
+			})
+			if err != nil {
+				t.Errorf("FAILED - expected %v but got %v", nil, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _ := c.Get("counter"); value != 20 {
+		t.Fatalf("FAILED - expected %d but got %d", 20, value)
+	}
+	if len(c.keyLocks.locks) != 0 {
+		t.Fatalf("FAILED - expected the lock table to be empty but it has %d locks", len(c.keyLocks.locks))
+	}
+}
+
+func TestCache_WithLockBusy(t *testing.T) {
+	c := New[int](time.Hour)
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = c.WithLock("key", time.Second, func(v int, found bool) (int, bool, error) {
+			close(held)
+			<-release
+			return 0, false, nil
+		})
+	}()
+	<-held
+
+	err := c.WithLock("key", time.Millisecond*10, func(v int, found bool) (int, bool, error) {
+		t.Error("FAILED - fn was called while the key was locked")
+		return 0, false, nil
+	})
+	if err != ErrKeyBusy {
+		t.Fatalf("FAILED - expected %v but got %v", ErrKeyBusy, err)
+	}
+	close(release)
+
+	failed := errors.New("failed")
+	err = c.WithLock("key", time.Second, func(v int, found bool) (int, bool, error) {
+		return 1, true, failed
+	})
+	if err != failed {
+		t.Fatalf("FAILED - expected %v but got %v", failed, err)
+	}
+	if _, found := c.Get("key"); found {
+		t.Fatal("FAILED - the value was stored when fn returned an error")
+	}
+}
+
+func TestCache_WithLockIndependentKeys(t *testing.T) {
+	c := New[int](time.Hour)
+
+	// Each call waits for the other to start, which can only happen if different keys do not block each other.
+	var started sync.WaitGroup
+	started.Add(2)
+	errs := make(chan error, 2)
+	for _, key := range []string{"a", "b"} {
+		go func() {
+			errs <- c.WithLock(key, time.Second, func(v int, found bool) (int, bool, error) {
+				started.Done()
+				started.Wait()
+				return 1, true, nil
+			})
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("FAILED - expected %v but got %v", nil, err)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("FAILED - locks on different keys blocked each other")
+		}
+	}
+}
+
+func TestCache_WithLockZeroTimeout(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 1000; i++ {
+		err := c.WithLock("key", 0, func(v int, found bool) (int, bool, error) {
+			return v + 1, true, nil
+		})
+		if err != nil {
+			t.Fatalf("FAILED - expected a free key to be locked but got %v", err)
+		}
+	}
+	if value, _ := c.Get("key"); value != 1000 {
+		t.Fatalf("FAILED - expected %d but got %d", 1000, value)
+	}
+}
+
+func TestCache_WithLockRejected(t *testing.T) {
+	type unitTest struct {
+		name     string
+		c        *Cache[*int]
+		expected error
+	}
+
+	tests := []unitTest{
+		{
+			name:     "No Overwrite",
+			c:        New[*int](time.Hour, WithNoOverwrite[*int]()),
+			expected: ErrKeyExists,
+		},
+		{
+			name:     "Reject Nil",
+			c:        New[*int](time.Hour, WithRejectNilValues[*int]()),
+			expected: ErrNilValue,
+		},
+	}
+
+	for _, test := range tests {
+		one := 1
+		test.c.Set("key", &one)
+		err := test.c.WithLock("key", time.Second, func(v *int, found bool) (*int, bool, error) {
+			return nil, true, nil
+		})
+		if !errors.Is(err, test.expected) {
+			t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, err)
+		}
+		if value, _ := test.c.Get("key"); value != &one {
+			t.Fatalf("%s FAILED - expected the stored value to be kept but got %v", test.name, value)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package simcache
+
+import "time"
+
+// CompareAndTouch resets the expiration of the item in the cache for a given key to the given TTL, or the default TTL
+// if none is given, but only if the item is live and holds expected. It returns whether the expiration was reset.
+// This lets the holder of a lease, identified by a token stored as the value, renew it without renewing a lease that
+// has since expired or been taken over by someone else.
+func CompareAndTouch[T comparable](c *Cache[T], key string, expected T, ttl ...time.Duration) bool {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	i, found := c.items[key]
+	if !found || c.expired(i) || i.value != expected {
+		return false
+	}
+
+	now := time.Now().UTC()
+	var clamped bool
+	i.expiration, clamped = c.clampExpiration(now, i.created, calculateExpiration(now, c.defaultTTL, ttl...))
+	i.written = now
+	c.items[key] = i
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	c.stats.recordWrite(clamped)
+	return true
+}
@@ -0,0 +1,75 @@
+package simcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareAndTouch(t *testing.T) {
+	type unitTest struct {
+		name     string
+		key      string
+		expected string
+		touched  bool
+	}
+
+	tests := []unitTest{
+		{
+			name:     "Holder",
+			key:      "lease",
+			expected: "token-1",
+			touched:  true,
+		},
+		{
+			name:     "Stale Holder",
+			key:      "lease",
+			expected: "token-0",
+			touched:  false,
+		},
+		{
+			name:     "Expired",
+			key:      "expired",
+			expected: "token-1",
+			touched:  false,
+		},
+		{
+			name:     "Missing",
+			key:      "missing",
+			expected: "token-1",
+			touched:  false,
+		},
+	}
+
+	for _, test := range tests {
+		c := New[string](time.Second)
+		c.Set("lease", "token-1")
+		c.Set("expired", "token-1", time.Nanosecond)
+		time.Sleep(time.Nanosecond * 2)
+
+		if touched := CompareAndTouch(c, test.key, test.expected, time.Hour); touched != test.touched {
+			t.Fatalf("%s FAILED - expected %t but got %t", test.name, test.touched, touched)
+		}
+		extended := time.Until(c.items["lease"].expiration) > time.Minute
+		if extended != (test.touched && test.key == "lease") {
+			t.Fatalf("%s FAILED - unexpected expiration %s", test.name, c.items["lease"].expiration)
+		}
+	}
+}
+
+func TestCompareAndTouch_Snapshot(t *testing.T) {
+	c := New[string](time.Hour, WithItemsSnapshot[string](), WithDirtyTracking[string]())
+	c.Set("lease", "token-1")
+	_ = c.Items()
+	c.ClearDirty()
+
+	if !CompareAndTouch(c, "lease", "token-1", time.Millisecond) {
+		t.Fatal("FAILED - expected the lease to be touched")
+	}
+	if dirty := c.DirtyKeys(); len(dirty) != 1 || dirty[0] != "lease" {
+		t.Fatalf("FAILED - expected %v but got %v", []string{"lease"}, dirty)
+	}
+	time.Sleep(time.Millisecond * 2)
+	if items := c.Items(); len(items) != 0 {
+		t.Fatalf("FAILED - expected the expired lease to be left out but got %v", items)
+	}
+}
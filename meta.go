@@ -0,0 +1,88 @@
+package simcache
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Meta holds information about an item in the cache, other than its value.
+type Meta struct {
+	// Created is when the item was written.
+	Created time.Time
+	// Expiration is when the item expires.
+	Expiration time.Time
+	// LastWrite is when the item was last written or had its TTL reset, for example by GetAndTouch.
+	LastWrite time.Time
+	// Origin is the code that wrote the item. It is only recorded when the cache was created WithOriginTracking.
+	Origin *Origin
+}
+
+// Origin is the location in the code that wrote an item to the cache.
+type Origin struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// String returns the origin formatted as file:line function.
+func (o Origin) String() string {
+	return fmt.Sprintf("%s:%d %s", o.File, o.Line, o.Function)
+}
+
+// Meta returns information about the live item in the cache for a given key, and if it was found.
+func (c *cache[T]) Meta(key string) (Meta, bool) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	i, found := c.items[key]
+	if !found || c.expired(i) {
+		return Meta{}, false
+	}
+	return Meta{
+		Created:    i.created,
+		Expiration: i.expiration,
+		LastWrite:  i.written,
+		Origin:     i.origin,
+	}, true
+}
+
+// Age returns how long ago the live item in the cache for a given key was written, and if it was found.
+func (c *cache[T]) Age(key string) (time.Duration, bool) {
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	i, found := c.items[key]
+	if !found || c.expired(i) {
+		return 0, false
+	}
+	return time.Since(i.created), true
+}
+
+// LastMutation returns when the cache's items were last changed by a write, delete, purge or TTL change, or the zero
+// time if they never were. Reading live items never changes it, but reads that delete expired items do. Writes
+// skipped by WithChangeDetection do not change it either.
+func (c *cache[T]) LastMutation() time.Time {
+	nanos := c.lastMutation.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// caller returns the origin of the code that called into the cache when the cache was created WithOriginTracking,
+// or nil otherwise. skip is the number of the cache's own frames between caller and that code.
+func (c *cache[T]) caller(skip int) *Origin {
+	if !c.trackOrigin {
+		return nil
+	}
+	pc, file, line, ok := runtime.Caller(skip + 1 + c.originDepth)
+	if !ok {
+		return nil
+	}
+	o := &Origin{File: file, Line: line}
+	if f := runtime.FuncForPC(pc); f != nil {
+		o.Function = f.Name()
+	}
+	return o
+}
@@ -0,0 +1,170 @@
+package simcache
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_Meta(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("one", 1, time.Minute)
+
+	meta, found := c.Meta("one")
+	if !found {
+		t.Fatal(`FAILED - "one" was not found`)
+	}
+	if meta.Expiration.Sub(meta.Created) != time.Minute {
+		t.Fatalf("FAILED - expected %s but got %s", time.Minute, meta.Expiration.Sub(meta.Created))
+	}
+	if meta.Origin != nil {
+		t.Fatal("FAILED - expected no origin without WithOriginTracking")
+	}
+
+	if _, found := c.Meta("missing"); found {
+		t.Fatal(`FAILED - "missing" was found`)
+	}
+}
+
+func TestCache_Age(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("one", 1)
+	c.Set("expired", 2, time.Nanosecond)
+	i := c.items["one"]
+	i.created = time.Now().UTC().Add(-time.Minute)
+	c.items["one"] = i
+	time.Sleep(time.Nanosecond * 2)
+
+	age, found := c.Age("one")
+	if !found || age < time.Minute || age > time.Minute+time.Second {
+		t.Fatalf("FAILED - expected an age of about %s but got %s, %t", time.Minute, age, found)
+	}
+	for _, k := range []string{"expired", "missing"} {
+		if _, found := c.Age(k); found {
+			t.Fatalf("FAILED - %q was found", k)
+		}
+	}
+}
+
+func TestCache_LastWrite(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("one", 1)
+	meta, _ := c.Meta("one")
+	if !meta.LastWrite.Equal(meta.Created) {
+		t.Fatalf("FAILED - expected %s but got %s", meta.Created, meta.LastWrite)
+	}
+
+	time.Sleep(time.Millisecond)
+	c.GetAndTouch("one")
+	touched, _ := c.Meta("one")
+	if !touched.LastWrite.After(meta.LastWrite) || !touched.Created.Equal(meta.Created) {
+		t.Fatalf("FAILED - expected only LastWrite to advance but got %+v", touched)
+	}
+}
+
+func TestCache_LastMutation(t *testing.T) {
+	c := New[int](time.Hour)
+	if last := c.LastMutation(); !last.IsZero() {
+		t.Fatalf("FAILED - expected the zero time but got %s", last)
+	}
+
+	type unitTest struct {
+		name    string
+		op      func()
+		changed bool
+	}
+
+	tests := []unitTest{
+		{name: "Set", op: func() { c.Set("one", 1) }, changed: true},
+		{name: "Get", op: func() { c.Get("one") }, changed: false},
+		{name: "Items", op: func() { c.Items() }, changed: false},
+		{name: "Meta", op: func() { c.Meta("one") }, changed: false},
+		{name: "Delete Missing", op: func() { c.Delete("missing") }, changed: false},
+		{name: "Purge Nothing", op: func() { c.Purge() }, changed: false},
+		{name: "GetAndTouch", op: func() { c.GetAndTouch("one") }, changed: true},
+		{name: "Add", op: func() { c.Add("two", 2, time.Nanosecond) }, changed: true},
+		{name: "Purge", op: func() { c.Purge() }, changed: true},
+		{name: "Delete", op: func() { c.Delete("one") }, changed: true},
+	}
+
+	for _, test := range tests {
+		before := c.LastMutation()
+		time.Sleep(time.Millisecond)
+		test.op()
+		if changed := !c.LastMutation().Equal(before); changed != test.changed {
+			t.Fatalf("%s FAILED - expected %t but got %t", test.name, test.changed, changed)
+		}
+	}
+}
+
+func TestCache_OriginTracking(t *testing.T) {
+	c := New[int](time.Hour, WithOriginTracking[int](0))
+
+	type unitTest struct {
+		name  string
+		write func() int
+	}
+
+	tests := []unitTest{
+		{
+			name: "Set",
+			write: func() int {
+				c.Set("key", 1)
+				return line()
+			},
+		},
+		{
+			name: "TrySet",
+			write: func() int {
+				_ = c.TrySet("key", 1)
+				return line()
+			},
+		},
+		{
+			name: "Add",
+			write: func() int {
+				c.Delete("key")
+				c.Add("key", 1)
+				return line()
+			},
+		},
+		{
+			name: "SetIfStillPresent",
+			write: func() int {
+				c.SetIfStillPresent("key", 1)
+				return line()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		expected := test.write() - 1
+		meta, _ := c.Meta("key")
+		if meta.Origin == nil {
+			t.Fatalf("%s FAILED - expected an origin", test.name)
+		}
+		if meta.Origin.Line != expected || !strings.HasSuffix(meta.Origin.File, "meta_test.go") {
+			t.Fatalf("%s FAILED - expected meta_test.go:%d but got %s", test.name, expected, meta.Origin)
+		}
+		if !strings.Contains(meta.Origin.Function, "TestCache_OriginTracking") {
+			t.Fatalf("%s FAILED - expected the test function but got %s", test.name, meta.Origin.Function)
+		}
+	}
+
+	wrapped := New[int](time.Hour, WithOriginTracking[int](1))
+	set := func(key string, value int) {
+		wrapped.Set(key, value)
+	}
+	set("key", 1)
+	expected := line() - 1
+	if meta, _ := wrapped.Meta("key"); meta.Origin.Line != expected {
+		t.Fatalf("FAILED - expected line %d but got %s", expected, meta.Origin)
+	}
+}
+
+// line returns the line it was called from.
+func line() int {
+	_, _, l, _ := runtime.Caller(1)
+	return l
+}
@@ -0,0 +1,64 @@
+package simcache
+
+import (
+	"time"
+	"unsafe"
+)
+
+// MoveTo moves the live items for the given keys from the cache to dst, keeping their expiration, and returns how
+// many were moved. Keys that are missing or expired are skipped and not created in dst. The expiration is clamped to
+// the TTL bounds of dst, as for any other write to it.
+// Both caches are locked for the whole move, so no other operation sees a key in both caches or in neither. They
+// are always locked in the same order, so concurrent moves in opposite directions do not deadlock.
+func (c *cache[T]) MoveTo(dst *Cache[T], keys ...string) int {
+	if dst.cache == c {
+		return 0
+	}
+	first, second := c, dst.cache
+	if uintptr(unsafe.Pointer(second)) < uintptr(unsafe.Pointer(first)) {
+		first, second = second, first
+	}
+	first.lock()
+	second.lock()
+
+	now := time.Now().UTC()
+	var replaced []replacement[T]
+	moved := 0
+	for _, k := range keys {
+		i, found := c.items[k]
+		if !found || c.expired(i) {
+			continue
+		}
+		delete(c.items, k)
+		c.changed()
+		c.markDirty(k)
+
+		var clamped bool
+		i.expiration, clamped = dst.clampExpiration(now, i.created, i.expiration)
+		k = dst.intern(k)
+		if old, ok := dst.store(k, i, clamped); ok {
+			replaced = append(replaced, replacement[T]{key: k, old: old, value: i.value})
+		}
+		moved++
+	}
+	if moved > 0 {
+		c.length.Store(int64(len(c.items)))
+		c.snapshot.Store(nil)
+	}
+	onReplace := dst.onReplace
+	second.mutex.Unlock()
+	first.mutex.Unlock()
+
+	if onReplace != nil {
+		for _, r := range replaced {
+			onReplace(r.key, r.old, r.value)
+		}
+	}
+	return moved
+}
+
+// replacement is an overwrite to report to the function set with OnReplace once the lock is released.
+type replacement[T any] struct {
+	key        string
+	old, value T
+}
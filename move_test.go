@@ -0,0 +1,85 @@
+package simcache
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_MoveTo(t *testing.T) {
+	hot := New[int](time.Hour)
+	cold := New[int](time.Hour, WithMaxTTL[int](time.Minute))
+	hot.Set("one", 1)
+	hot.Set("two", 2, time.Second*30)
+	hot.Set("expired", 3, time.Nanosecond)
+	cold.Set("two", 20)
+	time.Sleep(time.Nanosecond * 2)
+	expiration := hot.items["two"].expiration
+
+	if moved := hot.MoveTo(cold, "one", "two", "expired", "missing"); moved != 2 {
+		t.Fatalf("FAILED - expected %d but got %d", 2, moved)
+	}
+	if length := len(hot.items); length != 1 {
+		t.Fatalf("FAILED - expected %d items left but got %d", 1, length)
+	}
+	if value, found := cold.Get("two"); !found || value != 2 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 2, value, found)
+	}
+	if cold.items["two"].expiration != expiration {
+		t.Fatal(`FAILED - expected "two" to keep its expiration`)
+	}
+	if ttl := time.Until(cold.items["one"].expiration); ttl > time.Minute {
+		t.Fatalf("FAILED - expected the TTL of %q to be clamped but got %s", "one", ttl)
+	}
+	for _, k := range []string{"expired", "missing"} {
+		if _, found := cold.items[k]; found {
+			t.Fatalf("FAILED - %q was created in the destination", k)
+		}
+	}
+	if moved := cold.MoveTo(cold, "one"); moved != 0 {
+		t.Fatalf("FAILED - expected %d but got %d", 0, moved)
+	}
+}
+
+func TestCache_MoveToConcurrent(t *testing.T) {
+	const keys = 100
+	a := New[int](time.Hour)
+	b := New[int](time.Hour)
+	for i := 0; i < keys; i++ {
+		a.Set(strconv.Itoa(i), i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		src, dst := a, b
+		if g%2 == 1 {
+			src, dst = b, a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				src.MoveTo(dst, strconv.Itoa(rand.IntN(keys)), strconv.Itoa(rand.IntN(keys)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every key must be in exactly one of the caches, holding its original value.
+	if total := len(a.items) + len(b.items); total != keys {
+		t.Fatalf("FAILED - expected %d items in total but got %d", keys, total)
+	}
+	for i := 0; i < keys; i++ {
+		k := strconv.Itoa(i)
+		inA, foundA := a.items[k]
+		inB, foundB := b.items[k]
+		if foundA == foundB {
+			t.Fatalf("FAILED - %q is in both caches or neither", k)
+		}
+		if value := inA.value + inB.value; value != i {
+			t.Fatalf("FAILED - expected %d but got %d", i, value)
+		}
+	}
+}
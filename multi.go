@@ -0,0 +1,75 @@
+package simcache
+
+import (
+	"slices"
+	"time"
+)
+
+// Multi holds a set of values of type T for each key, backed by a Cache of slices. All of a key's values share one
+// TTL, and adding or removing a value is a single operation on the cache, so concurrent changes to the same key are
+// not lost.
+type Multi[T comparable] struct {
+	c *Cache[[]T]
+}
+
+// NewMulti creates an empty Multi where the TTL for each key's values will be set to the given duration.
+func NewMulti[T comparable](defaultTTL time.Duration, opts ...Option[[]T]) *Multi[T] {
+	return &Multi[T]{c: New[[]T](defaultTTL, opts...)}
+}
+
+// AddValue adds value to the values for a given key, unless it is already one of them, and resets the expiration of
+// all of the key's values to the given TTL, or the default TTL if none is given.
+func (m *Multi[T]) AddValue(key string, value T, ttl ...time.Duration) {
+	c := m.c
+	c.lock()
+	defer c.mutex.Unlock()
+
+	var values []T
+	if i, found := c.items[key]; found && !c.expired(i) {
+		values = i.value
+	}
+	if !slices.Contains(values, value) {
+		// Stored slices are never changed in place, so that values returned by GetValues stay valid.
+		values = append(slices.Clip(values), value)
+	}
+	i, clamped := c.newItem(values, ttl...)
+	c.store(c.intern(key), i, clamped)
+}
+
+// RemoveValue removes value from the values for a given key, keeping their expiration, and returns whether it was
+// there. The key is deleted once it has no values left.
+func (m *Multi[T]) RemoveValue(key string, value T) bool {
+	c := m.c
+	c.lock()
+	defer c.mutex.Unlock()
+
+	i, found := c.items[key]
+	if !found || c.expired(i) {
+		return false
+	}
+	n := slices.Index(i.value, value)
+	if n < 0 {
+		return false
+	}
+	if len(i.value) == 1 {
+		delete(c.items, key)
+		c.length.Store(int64(len(c.items)))
+	} else {
+		i.value = slices.Delete(slices.Clone(i.value), n, n+1)
+		i.written = time.Now().UTC()
+		c.items[key] = i
+	}
+	c.changed()
+	c.snapshot.Store(nil)
+	c.markDirty(key)
+	return true
+}
+
+// GetValues returns a copy of the live values for a given key, or nil if there are none.
+func (m *Multi[T]) GetValues(key string) []T {
+	values, found := m.c.Get(key)
+	if !found {
+		return nil
+	}
+	return slices.Clone(values)
+}
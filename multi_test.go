@@ -0,0 +1,74 @@
+package simcache
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMulti(t *testing.T) {
+	m := NewMulti[string](time.Hour)
+
+	type unitTest struct {
+		name     string
+		op       func()
+		expected []string
+	}
+
+	tests := []unitTest{
+		{name: "Add", op: func() { m.AddValue("user", "a") }, expected: []string{"a"}},
+		{name: "Add Another", op: func() { m.AddValue("user", "b") }, expected: []string{"a", "b"}},
+		{name: "Add Duplicate", op: func() { m.AddValue("user", "a") }, expected: []string{"a", "b"}},
+		{name: "Remove", op: func() { m.RemoveValue("user", "a") }, expected: []string{"b"}},
+		{name: "Remove Missing", op: func() { m.RemoveValue("user", "c") }, expected: []string{"b"}},
+		{name: "Remove Last", op: func() { m.RemoveValue("user", "b") }, expected: nil},
+	}
+
+	for _, test := range tests {
+		test.op()
+		if values := m.GetValues("user"); !slices.Equal(values, test.expected) {
+			t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, values)
+		}
+	}
+	if _, found := m.c.items["user"]; found {
+		t.Fatal(`FAILED - expected "user" to be deleted once it had no values`)
+	}
+
+	m.AddValue("expiring", "a", time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+	if values := m.GetValues("expiring"); values != nil {
+		t.Fatalf("FAILED - expected no values but got %v", values)
+	}
+	if m.RemoveValue("expiring", "a") {
+		t.Fatal("FAILED - expected an expired value not to be removed")
+	}
+}
+
+func TestMulti_Concurrent(t *testing.T) {
+	m := NewMulti[int](time.Hour)
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				m.AddValue("key", g*10+n)
+				values := m.GetValues("key")
+				values[0] = -1
+			}
+			m.RemoveValue("key", g*10)
+		}()
+	}
+	wg.Wait()
+
+	values := m.GetValues("key")
+	if len(values) != 90 || slices.Contains(values, -1) {
+		t.Fatalf("FAILED - expected %d values but got %d", 90, len(values))
+	}
+	for g := 0; g < 10; g++ {
+		if slices.Contains(values, g*10) {
+			t.Fatalf("FAILED - expected %d to be removed", g*10)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package simcache
+
+import (
+	"log/slog"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Option configures optional behaviour of a Cache. Options are passed to New.
+type Option[T any] func(*cache[T])
+
+// CacheConfig describes how a Cache was configured when it was created. MissFilterRate is the rate after clamping,
+// and LoadBuckets are the sorted bounds in use, which are the default buckets unless WithLoadBuckets was passed.
+type CacheConfig struct {
+	DefaultTTL    time.Duration
+	MinTTL        time.Duration
+	MaxTTL        time.Duration
+	MaxLifetime   time.Duration
+	Janitor       time.Duration
+	SlowLoad      time.Duration
+	ReadGrace     time.Duration
+	SnapshotLimit int
+	CleanupSample int
+
+	MissFilterItems int
+	MissFilterRate  float64
+	LoadBuckets     []time.Duration
+
+	ItemsSnapshot   bool
+	LockStats       bool
+	RejectNilValues bool
+	StrictTTL       bool
+	KeyInterning    bool
+	ChangeDetection bool
+	OriginTracking  bool
+	MissFilter      bool
+	DirtyTracking   bool
+	ReadOnlyGets    bool
+	CopyCheck       bool
+	NoOverwrite     bool
+	ValueHasher     bool
+}
+
+// Config returns how the cache was configured when it was created.
+func (c *cache[T]) Config() CacheConfig {
+	return CacheConfig{
+		DefaultTTL:      c.defaultTTL,
+		MinTTL:          c.minTTL,
+		MaxTTL:          c.maxTTL,
+		MaxLifetime:     c.maxLifetime,
+		Janitor:         c.janitorInterval(),
+		SlowLoad:        c.slowLoad,
+		ReadGrace:       c.readGrace,
+		SnapshotLimit:   c.snapshotLimit,
+		CleanupSample:   c.cleanupSample,
+		MissFilterItems: c.filterItems,
+		MissFilterRate:  c.filterRate,
+		LoadBuckets:     slices.Clone(c.stats.loads.bounds),
+		ItemsSnapshot:   c.snapshots,
+		LockStats:       c.lockStats,
+		RejectNilValues: c.rejectNilSet,
+		StrictTTL:       c.strictTTL,
+		KeyInterning:    c.internKeys,
+		ChangeDetection: c.equal != nil,
+		OriginTracking:  c.trackOrigin,
+		MissFilter:      c.filter.Load() != nil,
+		DirtyTracking:   c.dirty != nil,
+		ReadOnlyGets:    c.readOnlyGets,
+		CopyCheck:       c.self != nil,
+		NoOverwrite:     c.noOverwrite,
+		ValueHasher:     c.hasher != nil,
+	}
+}
+
+// WithItemsSnapshot makes Items return a cached copy of the cache's map that is only rebuilt after a write,
+// or once an item in it expires. This makes repeated calls to Items on a rarely changing cache cheap,
+// at the cost of every write invalidating the copy.
+// The map returned by Items is shared between callers and must not be modified.
+func WithItemsSnapshot[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.snapshots = true
+	}
+}
+
+// WithSnapshotLimit limits the number of live items ItemsChecked, KeysChecked and ValuesChecked will copy.
+// When the cache holds more than n live items they return ErrTooManyEntries rather than allocating a copy.
+// A limit of 0, the default, is unlimited. Items, Keys and Values are not affected.
+func WithSnapshotLimit[T any](n int) Option[T] {
+	return func(c *cache[T]) {
+		c.snapshotLimit = n
+	}
+}
+
+// WithMaxLifetime caps how long an item may live after it was written with Set or Add, regardless of its TTL
+// or of any later TTL extensions. Writing the key again starts a new lifetime.
+func WithMaxLifetime[T any](d time.Duration) Option[T] {
+	return func(c *cache[T]) {
+		c.maxLifetime = d
+	}
+}
+
+// WithLockStats records how long operations wait to acquire the cache's lock, reported by Stats.
+// Without it, no timing is done.
+func WithLockStats[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.lockStats = true
+	}
+}
+
+// WithRejectNilValues stops nil values from being stored. Add returns false and TrySet returns ErrNilValue
+// for a nil value, while Set ignores it. A non-nil interface holding a nil pointer counts as nil.
+// It has no effect when T is a type that cannot be nil.
+func WithRejectNilValues[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.rejectNil = isNillable(reflect.TypeFor[T]())
+		c.rejectNilSet = true
+	}
+}
+
+// WithMaxTTL caps the TTL of every item written to the cache. Longer TTLs, including the default TTL, are
+// clamped to d, or rejected by TrySet when the cache was created WithStrictTTL. New panics if d is less than the
+// TTL set with WithMinTTL.
+func WithMaxTTL[T any](d time.Duration) Option[T] {
+	return func(c *cache[T]) {
+		c.maxTTL = d
+	}
+}
+
+// WithMinTTL sets the shortest TTL of any item written to the cache. Shorter TTLs, including the default TTL, are
+// raised to d, or rejected by TrySet when the cache was created WithStrictTTL. New panics if d is greater than the
+// TTL set with WithMaxTTL.
+func WithMinTTL[T any](d time.Duration) Option[T] {
+	return func(c *cache[T]) {
+		c.minTTL = d
+	}
+}
+
+// WithStrictTTL makes TrySet return ErrTTLOutOfRange for a TTL outside of the bounds set by WithMinTTL and
+// WithMaxTTL, instead of clamping it. Set and Add still clamp.
+func WithStrictTTL[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.strictTTL = true
+	}
+}
+
+// WithKeyInterning stores a single canonical copy of each distinct key, shared by every cache using the option.
+// Keys that are substrings of larger strings, such as a request body, otherwise keep the whole string in memory
+// for as long as they are in the cache.
+func WithKeyInterning[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.internKeys = true
+	}
+}
+
+// WithChangeDetection makes Set compare the new value against the live value already in the cache using eq.
+// When they are equal only the item's expiration is refreshed, and the write is not treated as a change: the key is
+// not marked dirty, OnReplace is not called and LastMutation does not move. The snapshot kept by WithItemsSnapshot
+// stays valid unless the refresh makes the item expire earlier.
+// DeepEqual can be used for eq when T is not comparable.
+func WithChangeDetection[T any](eq func(old, value T) bool) Option[T] {
+	return func(c *cache[T]) {
+		c.equal = eq
+	}
+}
+
+// DeepEqual reports whether a and b are deeply equal using reflect.DeepEqual.
+// It can be passed to WithChangeDetection.
+func DeepEqual[T any](a, b T) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// WithOriginTracking records where in the code each item was written by Set, TrySet, Add or SetIfStillPresent,
+// returned by Meta. depth is the number of extra frames to skip, for callers that wrap the cache in their own
+// functions: with a depth of 0 the direct caller of the cache is recorded.
+func WithOriginTracking[T any](depth int) Option[T] {
+	return func(c *cache[T]) {
+		c.trackOrigin = true
+		c.originDepth = depth
+	}
+}
+
+// WithOpportunisticCleanup makes every Get also check a random sample of sampleSize other items, deleting those
+// that have expired. This keeps the number of expired items in the cache bounded without calling Purge, at the
+// cost of Get occasionally taking the write lock.
+func WithOpportunisticCleanup[T any](sampleSize int) Option[T] {
+	return func(c *cache[T]) {
+		c.cleanupSample = sampleSize
+	}
+}
+
+// WithValueHasher sets the function ContentHash uses to hash values.
+func WithValueHasher[T any](hasher func(T) uint64) Option[T] {
+	return func(c *cache[T]) {
+		c.hasher = hasher
+	}
+}
+
+// WithMissFilter keeps a bloom filter of the keys added to the cache, sized for expectedItems with the false positive
+// rate fpRate. Get consults it before taking the lock, so looking up a key that was never added is cheap.
+// Deleted and expired keys stay in the filter until it is rebuilt by Purge or PurgeCtx.
+// fpRate must be between 0 and 1; it is clamped to between 0.000001 and 0.5.
+func WithMissFilter[T any](expectedItems int, fpRate float64) Option[T] {
+	return func(c *cache[T]) {
+		fpRate = clampFilterRate(fpRate)
+		c.filterItems = expectedItems
+		c.filterRate = fpRate
+		c.filter.Store(newBloomFilter(expectedItems, fpRate))
+	}
+}
+
+// WithDirtyTracking records which keys are written, deleted, purged or given a new expiration, returned by DirtyKeys
+// until ClearDirty is called or SaveJSONL succeeds. Writes that WithChangeDetection finds unchanged are not recorded.
+func WithDirtyTracking[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.dirty = make(map[string]struct{})
+	}
+}
+
+// WithReadOnlyGets makes Get only take the read lock: expired items are not found, but are left in the cache for Purge
+// to delete. It also turns off the cleanup done by WithOpportunisticCleanup.
+func WithReadOnlyGets[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.readOnlyGets = true
+	}
+}
+
+// WithReadGrace keeps expired items in the cache for the grace period d after they expire. They are not found by Get
+// or the other methods that read the cache, but GetStale still returns them, and they are only deleted once the grace
+// period has passed. Items invalidated by NextGeneration get no grace period.
+func WithReadGrace[T any](d time.Duration) Option[T] {
+	return func(c *cache[T]) {
+		c.readGrace = d
+	}
+}
+
+// WithCopyCheck makes the cache panic when it is used through a copy of its internal state, for example after
+// dereferencing and copying a Cache. A copy shares the original's items but not its lock, so using it is a data race.
+// go vet's copylocks check reports most such copies at build time; this catches the rest at run time.
+func WithCopyCheck[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.self = c
+	}
+}
+
+// WithJanitor starts a goroutine that deletes expired items from the cache every interval, as Purge does, so that items
+// which are never read again do not stay in memory. A tick is skipped if a Purge is already running. Call Close to stop
+// it; it is also stopped once the Cache is garbage collected. An interval of 0 or less starts no janitor.
+func WithJanitor[T any](interval time.Duration) Option[T] {
+	return func(c *cache[T]) {
+		if interval > 0 {
+			c.janitor = &janitor{interval: interval}
+		}
+	}
+}
+
+// WithNoOverwrite makes the cache write-once: Set panics and TrySet returns ErrKeyExists instead of overwriting a live
+// item, so duplicate writes are caught as bugs. Expired items can still be replaced. Use it only for caches whose
+// entries should never be updated; other writes, such as SetIfStillPresent and ReplaceAll, are not affected.
+func WithNoOverwrite[T any]() Option[T] {
+	return func(c *cache[T]) {
+		c.noOverwrite = true
+	}
+}
+
+// WithLoadBuckets sets the upper bounds of the buckets of the load latency histogram returned by Stats, replacing the
+// default buckets, which double from 1µs to about 33s. Loads longer than the last bound are counted in an extra bucket.
+func WithLoadBuckets[T any](bounds ...time.Duration) Option[T] {
+	return func(c *cache[T]) {
+		c.stats.loads = newHistogram(bounds)
+	}
+}
+
+// WithSlowLoadThreshold calls fn with the key and duration of every call by GetOrCompute, Memoize or Once to a load
+// function that takes longer than d. fn is called by the goroutine that ran the load, after it returns and before
+// waiting callers are released. If fn is nil, a warning is logged with slog's default logger instead.
+func WithSlowLoadThreshold[T any](d time.Duration, fn func(key string, took time.Duration)) Option[T] {
+	return func(c *cache[T]) {
+		if fn == nil {
+			fn = func(key string, took time.Duration) {
+				slog.Warn("simcache slow load", slog.String("key", key), slog.Duration("took", took))
+			}
+		}
+		c.slowLoad = d
+		c.onSlowLoad = fn
+	}
+}
@@ -0,0 +1,61 @@
+package simcache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_Config(t *testing.T) {
+	c := New[int](time.Minute)
+	expected := CacheConfig{DefaultTTL: time.Minute, LoadBuckets: defaultLoadBuckets}
+	if actual := c.Config(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("FAILED - expected %+v but got %+v", expected, actual)
+	}
+
+	c = New[int](time.Minute,
+		WithMaxTTL[int](time.Hour),
+		WithMinTTL[int](time.Second),
+		WithMaxLifetime[int](time.Hour*2),
+		WithSnapshotLimit[int](10),
+		WithItemsSnapshot[int](),
+		WithLockStats[int](),
+		WithStrictTTL[int](),
+		WithKeyInterning[int](),
+		WithChangeDetection(DeepEqual[int]),
+		WithReadOnlyGets[int](),
+		WithNoOverwrite[int](),
+		WithRejectNilValues[int](),
+		WithValueHasher(func(v int) uint64 { return uint64(v) }),
+		WithMissFilter[int](1000, 2),
+		WithLoadBuckets[int](time.Second, time.Millisecond, time.Second),
+	)
+	expected = CacheConfig{
+		DefaultTTL:      time.Minute,
+		MinTTL:          time.Second,
+		MaxTTL:          time.Hour,
+		MaxLifetime:     time.Hour * 2,
+		SnapshotLimit:   10,
+		ItemsSnapshot:   true,
+		LockStats:       true,
+		StrictTTL:       true,
+		KeyInterning:    true,
+		ChangeDetection: true,
+		ReadOnlyGets:    true,
+		NoOverwrite:     true,
+		RejectNilValues: true,
+		ValueHasher:     true,
+		MissFilter:      true,
+		MissFilterItems: 1000,
+		MissFilterRate:  0.5,
+		LoadBuckets:     []time.Duration{time.Millisecond, time.Second},
+	}
+	if actual := c.Config(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("FAILED - expected %+v but got %+v", expected, actual)
+	}
+
+	pointers := New[*int](time.Minute, WithRejectNilValues[*int]())
+	if !pointers.Config().RejectNilValues {
+		t.Fatal("FAILED - expected RejectNilValues to be enabled")
+	}
+}
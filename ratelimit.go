@@ -0,0 +1,68 @@
+package simcache
+
+import (
+	"strconv"
+	"time"
+)
+
+// RateLimiter allows up to a limit of events per key within a window, keeping its counters in a Cache.
+// The counters expire with their windows, so keys that stop being used do not need to be cleaned up.
+type RateLimiter struct {
+	c       *Cache[int64]
+	limit   int64
+	window  time.Duration
+	sliding bool
+}
+
+// NewRateLimiter returns a fixed-window RateLimiter backed by c. Each key's window starts with its first event and
+// lasts for window, after which the key's count starts again from zero.
+// It panics if window is not positive or limit is negative. A limit of 0 allows no events.
+func NewRateLimiter(c *Cache[int64], limit int64, window time.Duration) *RateLimiter {
+	checkRateLimit(limit, window)
+	return &RateLimiter{c: c, limit: limit, window: window}
+}
+
+// NewSlidingRateLimiter returns a sliding-window RateLimiter backed by c. It counts events in windows aligned to
+// multiples of window, and weights the previous window's count by how much of it still overlaps the last window
+// duration, so a burst at the end of one window also limits the start of the next.
+// It panics if window is not positive or limit is negative. A limit of 0 allows no events.
+func NewSlidingRateLimiter(c *Cache[int64], limit int64, window time.Duration) *RateLimiter {
+	checkRateLimit(limit, window)
+	return &RateLimiter{c: c, limit: limit, window: window, sliding: true}
+}
+
+// checkRateLimit panics if a RateLimiter cannot be created with the given limit and window.
+func checkRateLimit(limit int64, window time.Duration) {
+	if window <= 0 {
+		panic("simcache: non-positive window for RateLimiter")
+	}
+	if limit < 0 {
+		panic("simcache: negative limit for RateLimiter")
+	}
+}
+
+// Allow records an event for a given key and returns true, unless the key has reached its limit within the
+// current window, in which case nothing is recorded and it returns false.
+func (l *RateLimiter) Allow(key string) bool {
+	if !l.sliding {
+		_, allowed := IncrementWithCap(l.c, key, 1, l.limit, l.window)
+		return allowed
+	}
+
+	now := time.Now().UnixNano()
+	n := now / int64(l.window)
+	elapsed := float64(now%int64(l.window)) / float64(l.window)
+	current := key + "#" + strconv.FormatInt(n, 10)
+	previous := key + "#" + strconv.FormatInt(n-1, 10)
+
+	l.c.lock()
+	defer l.c.mutex.Unlock()
+
+	weighted := float64(counter(l.c, previous))*(1-elapsed) + float64(counter(l.c, current))
+	if weighted+1 > float64(l.limit) {
+		return false
+	}
+	// The counter is still needed as the previous window once its own window has ended.
+	increment(l.c, current, 1, l.window*2)
+	return true
+}
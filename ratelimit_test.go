@@ -0,0 +1,99 @@
+package simcache
+
+import (
+	"testing"
+	"time"
+)
+
+// sleepUntilWindow sleeps until offset into the next window of the given length.
+func sleepUntilWindow(window, offset time.Duration) {
+	now := time.Now().UnixNano()
+	time.Sleep(time.Duration(int64(window)-now%int64(window)) + offset)
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	const window = time.Millisecond * 100
+
+	type unitTest struct {
+		name    string
+		l       *RateLimiter
+		allowed []bool
+		// afterBoundary is whether the first event just after the next window boundary is allowed.
+		afterBoundary bool
+	}
+
+	tests := []unitTest{
+		{
+			name:          "Fixed Window",
+			l:             NewRateLimiter(New[int64](time.Hour), 4, window),
+			allowed:       []bool{true, true, true, true, false},
+			afterBoundary: true,
+		},
+		{
+			name:          "Sliding Window",
+			l:             NewSlidingRateLimiter(New[int64](time.Hour), 4, window),
+			allowed:       []bool{true, true, true, true, false},
+			afterBoundary: false,
+		},
+	}
+
+	for _, test := range tests {
+		sleepUntilWindow(window, time.Millisecond)
+		for n, expected := range test.allowed {
+			if allowed := test.l.Allow("user"); allowed != expected {
+				t.Fatalf("%s FAILED - event %d: expected %t but got %t", test.name, n, expected, allowed)
+			}
+		}
+		if !test.l.Allow("other") {
+			t.Fatalf("%s FAILED - expected keys to be limited separately", test.name)
+		}
+
+		// The fixed window has ended, but most of the burst is still within the last window duration.
+		sleepUntilWindow(window, time.Millisecond*5)
+		if allowed := test.l.Allow("user"); allowed != test.afterBoundary {
+			t.Fatalf("%s FAILED - expected %t after the boundary but got %t", test.name, test.afterBoundary, allowed)
+		}
+
+		// Two windows later, the burst no longer counts at all.
+		sleepUntilWindow(window, window+time.Millisecond)
+		if !test.l.Allow("user") {
+			t.Fatalf("%s FAILED - expected the limit to reset", test.name)
+		}
+	}
+}
+
+func TestNewRateLimiter_Invalid(t *testing.T) {
+	type unitTest struct {
+		name   string
+		limit  int64
+		window time.Duration
+		panics bool
+	}
+
+	tests := []unitTest{
+		{name: "Zero Window", limit: 1, window: 0, panics: true},
+		{name: "Negative Window", limit: 1, window: -time.Second, panics: true},
+		{name: "Negative Limit", limit: -1, window: time.Second, panics: true},
+		{name: "Zero Limit", limit: 0, window: time.Second, panics: false},
+	}
+
+	constructors := map[string]func(c *Cache[int64], limit int64, window time.Duration) *RateLimiter{
+		"Fixed":   NewRateLimiter,
+		"Sliding": NewSlidingRateLimiter,
+	}
+	for name, newLimiter := range constructors {
+		for _, test := range tests {
+			func() {
+				defer func() {
+					if r := recover(); (r != nil) != test.panics {
+						t.Fatalf("%s %s FAILED - expected a panic %t but got %v", name, test.name, test.panics, r)
+					}
+				}()
+				l := newLimiter(New[int64](time.Hour), test.limit, test.window)
+				if l.Allow("user") {
+					t.Fatalf("%s %s FAILED - expected a limit of 0 to allow nothing", name, test.name)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package simcache
+
+// OnReplace sets a function to be called when Set, TrySet, SetIfStillPresent, ReplaceFunc or ReplaceAll overwrites a
+// live item, passing both the old and new values, for example to release resources held by the old value.
+// It is called after the new value is stored and outside of the lock, so it may use the cache. Passing nil removes it.
+// It is not called for items that are deleted or expire.
+// Overwriting an item with an equal value counts too; only a cache created WithChangeDetection skips such writes.
+func (c *cache[T]) OnReplace(fn func(key string, old, value T)) {
+	c.lock()
+	defer c.mutex.Unlock()
+
+	c.onReplace = fn
+}
@@ -0,0 +1,85 @@
+package simcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_OnReplace(t *testing.T) {
+	type replacement struct {
+		key        string
+		old, value int
+	}
+
+	type unitTest struct {
+		name     string
+		c        *Cache[int]
+		write    func(c *Cache[int])
+		expected []replacement
+	}
+
+	tests := []unitTest{
+		{
+			name:     "New Key",
+			c:        New[int](time.Hour),
+			write:    func(c *Cache[int]) { c.Set("two", 2) },
+			expected: nil,
+		},
+		{
+			name:     "Set",
+			c:        New[int](time.Hour),
+			write:    func(c *Cache[int]) { c.Set("one", 2) },
+			expected: []replacement{{key: "one", old: 1, value: 2}},
+		},
+		{
+			name:     "SetIfStillPresent",
+			c:        New[int](time.Hour),
+			write:    func(c *Cache[int]) { c.SetIfStillPresent("one", 3) },
+			expected: []replacement{{key: "one", old: 1, value: 3}},
+		},
+		{
+			name:     "ReplaceFunc",
+			c:        New[int](time.Hour),
+			write:    func(c *Cache[int]) { c.ReplaceFunc(func(string, int) bool { return true }, 4) },
+			expected: []replacement{{key: "one", old: 1, value: 4}},
+		},
+		{
+			name:     "Unchanged Value",
+			c:        New[int](time.Hour, WithChangeDetection[int](func(old, value int) bool { return old == value })),
+			write:    func(c *Cache[int]) { c.Set("one", 1) },
+			expected: nil,
+		},
+		{
+			name: "Expired Item",
+			c:    New[int](time.Hour),
+			write: func(c *Cache[int]) {
+				c.ExpireAll()
+				time.Sleep(time.Nanosecond * 2)
+				c.Set("one", 5)
+			},
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		test.c.Set("one", 1)
+		var replaced []replacement
+		test.c.OnReplace(func(key string, old, value int) {
+			// The lock is released, so the cache can be used from the callback.
+			if current, _ := test.c.Get(key); current != value {
+				t.Fatalf("%s FAILED - expected %d to be stored but got %d", test.name, value, current)
+			}
+			replaced = append(replaced, replacement{key: key, old: old, value: value})
+		})
+		test.write(test.c)
+
+		if len(replaced) != len(test.expected) {
+			t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected, replaced)
+		}
+		for i := range replaced {
+			if replaced[i] != test.expected[i] {
+				t.Fatalf("%s FAILED - expected %v but got %v", test.name, test.expected[i], replaced[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package simcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds statistics about a cache's operations.
+// Lock statistics are only recorded when the cache was created WithLockStats.
+type Stats struct {
+	// ClampedWrites is the number of items written with a TTL that was clamped by WithMinTTL or WithMaxTTL.
+	ClampedWrites uint64
+	// LockWaits is the number of times the cache's lock was acquired.
+	LockWaits uint64
+	// LockWaitTotal is the total time spent waiting to acquire the cache's lock.
+	LockWaitTotal time.Duration
+	// LockWaitMax is the longest time spent waiting to acquire the cache's lock.
+	LockWaitMax time.Duration
+	// LockWaitP50, LockWaitP95 and LockWaitP99 estimate the median, 95th and 99th percentile times spent waiting to
+	// acquire the cache's lock, as the upper bound of the histogram bucket each falls in. The buckets double from
+	// 100ns.
+	LockWaitP50 time.Duration
+	LockWaitP95 time.Duration
+	LockWaitP99 time.Duration
+	// Loads is the number of times GetOrCompute, Memoize or Once called their function to load a missing value,
+	// including loads that returned an error.
+	Loads uint64
+	// LoadP50, LoadP95 and LoadP99 estimate the median, 95th and 99th percentile load latencies, as the upper bound of
+	// the histogram bucket each falls in.
+	LoadP50 time.Duration
+	LoadP95 time.Duration
+	LoadP99 time.Duration
+	// LoadMax is the longest load.
+	LoadMax time.Duration
+	// LoadBuckets is the histogram of load latencies, with the buckets set by WithLoadBuckets.
+	LoadBuckets []LoadBucket
+}
+
+// LockWaitAverage returns the average time spent waiting to acquire the cache's lock.
+func (s Stats) LockWaitAverage() time.Duration {
+	if s.LockWaits == 0 {
+		return 0
+	}
+	return s.LockWaitTotal / time.Duration(s.LockWaits)
+}
+
+// Stats returns a snapshot of the cache's statistics.
+func (c *cache[T]) Stats() Stats {
+	waits := c.stats.waits.buckets()
+	waitMax := time.Duration(c.stats.lockWaitMax.Load())
+	buckets := c.stats.loads.buckets()
+	loadMax := time.Duration(c.stats.loads.max.Load())
+	var loads uint64
+	for _, b := range buckets {
+		loads += b.Count
+	}
+	return Stats{
+		ClampedWrites: c.stats.clampedWrites.Load(),
+		LockWaits:     c.stats.lockWaits.Load(),
+		LockWaitTotal: time.Duration(c.stats.lockWaitTotal.Load()),
+		LockWaitMax:   waitMax,
+		LockWaitP50:   percentile(waits, 0.50, waitMax),
+		LockWaitP95:   percentile(waits, 0.95, waitMax),
+		LockWaitP99:   percentile(waits, 0.99, waitMax),
+		Loads:         loads,
+		LoadP50:       percentile(buckets, 0.50, loadMax),
+		LoadP95:       percentile(buckets, 0.95, loadMax),
+		LoadP99:       percentile(buckets, 0.99, loadMax),
+		LoadMax:       loadMax,
+		LoadBuckets:   buckets,
+	}
+}
+
+type stats struct {
+	clampedWrites atomic.Uint64
+	lockWaits     atomic.Uint64
+	lockWaitTotal atomic.Int64
+	lockWaitMax   atomic.Int64
+	waits         *histogram
+	loads         *histogram
+}
+
+func (s *stats) recordWrite(clamped bool) {
+	if clamped {
+		s.clampedWrites.Add(1)
+	}
+}
+
+func (s *stats) recordLockWait(d time.Duration) {
+	s.lockWaits.Add(1)
+	s.lockWaitTotal.Add(int64(d))
+	storeMax(&s.lockWaitMax, int64(d))
+	s.waits.record(d)
+}
+
+// recordLoad records how long a call to a load function took, and calls the cache's slow load function if it took
+// longer than the threshold set with WithSlowLoadThreshold.
+func (c *cache[T]) recordLoad(key string, took time.Duration) {
+	c.stats.loads.record(took)
+	if c.slowLoad > 0 && took > c.slowLoad {
+		c.onSlowLoad(key, took)
+	}
+}
+
+// lock acquires the cache's write lock, recording how long it waited when lock statistics are enabled.
+func (c *cache[T]) lock() {
+	c.checkCopy()
+	if !c.lockStats {
+		c.mutex.Lock()
+		return
+	}
+	start := time.Now()
+	c.mutex.Lock()
+	c.stats.recordLockWait(time.Since(start))
+}
+
+// rlock acquires the cache's read lock, recording how long it waited when lock statistics are enabled.
+func (c *cache[T]) rlock() {
+	c.checkCopy()
+	if !c.lockStats {
+		c.mutex.RLock()
+		return
+	}
+	start := time.Now()
+	c.mutex.RLock()
+	c.stats.recordLockWait(time.Since(start))
+}
@@ -0,0 +1,138 @@
+package simcache
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_LockStats(t *testing.T) {
+	c := New[int](time.Hour)
+	c.Set("one", 1)
+	if waits := c.Stats().LockWaits; waits != 0 {
+		t.Fatalf("FAILED - expected %d but got %d", 0, waits)
+	}
+
+	c = New[int](time.Hour, WithLockStats[int]())
+	c.Set("one", 1)
+	c.Get("one")
+	if waits := c.Stats().LockWaits; waits != 2 {
+		t.Fatalf("FAILED - expected %d but got %d", 2, waits)
+	}
+
+	// Hold the lock so the next Set has to wait for it.
+	c.mutex.Lock()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Set("two", 2)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	c.mutex.Unlock()
+	wg.Wait()
+
+	stats := c.Stats()
+	if stats.LockWaitMax < time.Millisecond*5 {
+		t.Fatalf("FAILED - expected a wait of at least %s but got %s", time.Millisecond*5, stats.LockWaitMax)
+	}
+	if stats.LockWaitAverage() <= 0 || stats.LockWaitAverage() > stats.LockWaitMax {
+		t.Fatalf("FAILED - unexpected average wait %s", stats.LockWaitAverage())
+	}
+	// Only one of the three waits was long, so it sets the 99th percentile but not the median.
+	if stats.LockWaitP99 != stats.LockWaitMax {
+		t.Fatalf("FAILED - expected a p99 of %s but got %s", stats.LockWaitMax, stats.LockWaitP99)
+	}
+	if stats.LockWaitP50 >= time.Millisecond || stats.LockWaitP50 > stats.LockWaitP95 {
+		t.Fatalf("FAILED - expected a short median wait but got %s", stats.LockWaitP50)
+	}
+}
+
+func TestCache_LoadStats(t *testing.T) {
+	type slowLoad struct {
+		key  string
+		took time.Duration
+	}
+
+	var mutex sync.Mutex
+	var slow []slowLoad
+	c := New[int](time.Hour,
+		WithLoadBuckets[int](time.Millisecond*200, time.Millisecond*10, time.Millisecond*50),
+		WithSlowLoadThreshold[int](time.Millisecond*15, func(key string, took time.Duration) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			slow = append(slow, slowLoad{key: key, took: took})
+		}),
+	)
+
+	sleeps := []time.Duration{0, 0, 0, time.Millisecond * 20, time.Millisecond * 100}
+	for n, d := range sleeps {
+		_, _ = c.GetOrCompute(strconv.Itoa(n), func() (int, error) {
+			time.Sleep(d)
+			return n, nil
+		})
+	}
+	// Hits do not load.
+	_, _ = c.GetOrCompute("0", func() (int, error) { return 0, nil })
+
+	stats := c.Stats()
+	if stats.Loads != uint64(len(sleeps)) {
+		t.Fatalf("FAILED - expected %d loads but got %d", len(sleeps), stats.Loads)
+	}
+	expected := []LoadBucket{
+		{UpperBound: time.Millisecond * 10, Count: 3},
+		{UpperBound: time.Millisecond * 50, Count: 1},
+		{UpperBound: time.Millisecond * 200, Count: 1},
+		{UpperBound: time.Duration(math.MaxInt64), Count: 0},
+	}
+	if !slices.Equal(stats.LoadBuckets, expected) {
+		t.Fatalf("FAILED - expected %v but got %v", expected, stats.LoadBuckets)
+	}
+	if stats.LoadP50 != time.Millisecond*10 {
+		t.Fatalf("FAILED - expected %s but got %s", time.Millisecond*10, stats.LoadP50)
+	}
+	if stats.LoadMax < time.Millisecond*100 || stats.LoadP99 != stats.LoadMax {
+		t.Fatalf("FAILED - expected a p99 of %s but got %s", stats.LoadMax, stats.LoadP99)
+	}
+
+	if len(slow) != 2 || slow[0].key != "3" || slow[1].key != "4" {
+		t.Fatalf("FAILED - expected slow loads of %q and %q but got %v", "3", "4", slow)
+	}
+	if slow[0].took < time.Millisecond*20 || slow[1].took < time.Millisecond*100 {
+		t.Fatalf("FAILED - expected slow loads of at least %s and %s but got %v", time.Millisecond*20, time.Millisecond*100, slow)
+	}
+	if c.Config().SlowLoad != time.Millisecond*15 {
+		t.Fatalf("FAILED - expected %s but got %s", time.Millisecond*15, c.Config().SlowLoad)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	type unitTest struct {
+		name     string
+		records  []time.Duration
+		q        float64
+		expected time.Duration
+	}
+
+	tests := []unitTest{
+		{name: "Empty", records: nil, q: 0.5, expected: 0},
+		{name: "Median", records: []time.Duration{1, 1, 3, 3, 5}, q: 0.5, expected: 4},
+		{name: "Lower Median", records: []time.Duration{1, 1, 1, 3, 3}, q: 0.5, expected: 2},
+		{name: "Capped At Max", records: []time.Duration{5}, q: 0.99, expected: 5},
+		{name: "Overflow", records: []time.Duration{1, 20}, q: 0.99, expected: 20},
+	}
+
+	for _, test := range tests {
+		h := newHistogram([]time.Duration{2, 4, 8})
+		for _, d := range test.records {
+			h.record(d)
+		}
+		actual := percentile(h.buckets(), test.q, time.Duration(h.max.Load()))
+		if actual != test.expected {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package simcache
+
+import (
+	"maps"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stressOp is an operation run against the cache by stressWorker, chosen at random in proportion to its weight.
+type stressOp struct {
+	name   string
+	weight int
+	run    func(w *stressWorker)
+}
+
+// stressWorker runs random operations against a shared cache. Each worker owns a disjoint set of keys and keeps a
+// shadow map of what the cache should hold for them, so every read of its own keys can be checked exactly while
+// other workers write theirs. Keys with a very short TTL are also written, outside of the shadow, so that reads and
+// purges race with expiry.
+type stressWorker struct {
+	t      *testing.T
+	c      *Cache[int]
+	id     int
+	rand   *rand.Rand
+	shadow map[string]int
+}
+
+func (w *stressWorker) key() string {
+	return strconv.Itoa(w.id) + ":" + strconv.Itoa(w.rand.IntN(32))
+}
+
+func (w *stressWorker) owns(key string) bool {
+	prefix := strconv.Itoa(w.id) + ":"
+	return len(key) > len(prefix) && key[:len(prefix)] == prefix
+}
+
+// checkOwned fails the test if the keys of the worker seen in items do not match its shadow.
+func (w *stressWorker) checkOwned(op string, items map[string]int) {
+	owned := make(map[string]int)
+	for k, v := range items {
+		if w.owns(k) {
+			owned[k] = v
+		}
+	}
+	if !maps.Equal(owned, w.shadow) {
+		w.t.Errorf("%s FAILED - expected %v but got %v", op, w.shadow, owned)
+	}
+}
+
+var stressOps = []stressOp{
+	{name: "Get", weight: 40, run: func(w *stressWorker) {
+		k := w.key()
+		value, found := w.c.Get(k)
+		expected, ok := w.shadow[k]
+		if found != ok || (found && value != expected) {
+			w.t.Errorf("Get FAILED - expected %d, %t but got %d, %t", expected, ok, value, found)
+		}
+	}},
+	{name: "Set", weight: 20, run: func(w *stressWorker) {
+		k, v := w.key(), w.rand.Int()
+		w.c.Set(k, v)
+		w.shadow[k] = v
+	}},
+	{name: "Add", weight: 10, run: func(w *stressWorker) {
+		k, v := w.key(), w.rand.Int()
+		_, exists := w.shadow[k]
+		if added := w.c.Add(k, v); added == exists {
+			w.t.Errorf("Add FAILED - expected %t but got %t", !exists, added)
+		}
+		if !exists {
+			w.shadow[k] = v
+		}
+	}},
+	{name: "Delete", weight: 10, run: func(w *stressWorker) {
+		k := w.key()
+		w.c.Delete(k)
+		delete(w.shadow, k)
+	}},
+	{name: "SetExpiring", weight: 5, run: func(w *stressWorker) {
+		w.c.Set("tmp:"+strconv.Itoa(w.rand.IntN(32)), 0, time.Nanosecond)
+	}},
+	{name: "Items", weight: 5, run: func(w *stressWorker) {
+		w.checkOwned("Items", w.c.Items())
+	}},
+	{name: "Keys", weight: 4, run: func(w *stressWorker) {
+		items := make(map[string]int)
+		for _, k := range w.c.Keys() {
+			// Keys does not return values, so a deleted key shows up as a mismatch with the zero value.
+			if w.owns(k) {
+				items[k] = w.shadow[k]
+			}
+		}
+		w.checkOwned("Keys", items)
+	}},
+	{name: "Purge", weight: 3, run: func(w *stressWorker) {
+		w.c.Purge()
+	}},
+	{name: "PurgeAtomic", weight: 3, run: func(w *stressWorker) {
+		w.c.PurgeAtomic()
+	}},
+}
+
+func TestCache_Stress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	const workers, iterations = 8, 2000
+	total := 0
+	for _, op := range stressOps {
+		total += op.weight
+	}
+	c := New[int](time.Hour)
+
+	shadows := make([]map[string]int, workers)
+	var wg sync.WaitGroup
+	for id := 0; id < workers; id++ {
+		w := &stressWorker{t: t, c: c, id: id, rand: rand.New(rand.NewPCG(uint64(id), 1)), shadow: make(map[string]int)}
+		shadows[id] = w.shadow
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < iterations; n++ {
+				pick := w.rand.IntN(total)
+				for _, op := range stressOps {
+					if pick < op.weight {
+						op.run(w)
+						break
+					}
+					pick -= op.weight
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Once every worker has stopped, the cache must hold exactly the union of the shadows.
+	time.Sleep(time.Nanosecond * 2)
+	expected := make(map[string]int)
+	for _, shadow := range shadows {
+		maps.Copy(expected, shadow)
+	}
+	if items := c.Items(); !maps.Equal(items, expected) {
+		t.Fatalf("FAILED - expected %d items but got %d", len(expected), len(items))
+	}
+	if length := c.Len(); length != len(expected) {
+		t.Fatalf("FAILED - expected %d but got %d", len(expected), length)
+	}
+}
+
+func FuzzCache_Keys(f *testing.F) {
+	for _, key := range []string{"", "one", "user:1", "\x00", "日本語", string(make([]byte, 1024))} {
+		f.Add(key, 1)
+	}
+
+	f.Fuzz(func(t *testing.T, key string, value int) {
+		caches := []*Cache[int]{
+			New[int](time.Hour),
+			New[int](time.Hour, WithKeyInterning[int]()),
+			New[int](time.Hour, WithMissFilter[int](16, 0.01)),
+		}
+		for _, c := range caches {
+			c.Set(key, value)
+			if got, found := c.Get(key); !found || got != value {
+				t.Fatalf("FAILED - expected %d but got %d, %t", value, got, found)
+			}
+			if items := c.Items(); len(items) != 1 || items[key] != value {
+				t.Fatalf("FAILED - unexpected items %v", items)
+			}
+			c.Delete(key)
+			if _, found := c.Get(key); found {
+				t.Fatalf("FAILED - %q was found after it was deleted", key)
+			}
+		}
+	})
+}
+
+func FuzzCalculateExpiration(f *testing.F) {
+	f.Add(int64(0), int64(time.Minute), int64(0))
+	f.Add(int64(1700000000000000000), int64(time.Minute), int64(time.Hour))
+	f.Add(int64(1700000000000000000), int64(time.Minute), int64(-time.Hour))
+
+	// Limit durations to a century either way, so that adding them to now cannot overflow.
+	const limit = int64(time.Hour * 24 * 365 * 100)
+	f.Fuzz(func(t *testing.T, nanos, defaultTTL, ttl int64) {
+		if defaultTTL < -limit || defaultTTL > limit || ttl < -limit || ttl > limit {
+			t.Skip()
+		}
+		now := time.Unix(0, nanos)
+		expected := time.Duration(defaultTTL)
+		if ttl > 0 {
+			expected = time.Duration(ttl)
+		}
+
+		expiration := calculateExpiration(now, time.Duration(defaultTTL), time.Duration(ttl))
+		if expiration.Location() != time.UTC {
+			t.Fatalf("FAILED - expected UTC but got %s", expiration.Location())
+		}
+		if d := expiration.Sub(now); d != expected {
+			t.Fatalf("FAILED - expected %s but got %s", expected, d)
+		}
+		if without := calculateExpiration(now, time.Duration(defaultTTL)); without.Sub(now) != time.Duration(defaultTTL) {
+			t.Fatalf("FAILED - expected %s but got %s", time.Duration(defaultTTL), without.Sub(now))
+		}
+	})
+}
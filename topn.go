@@ -0,0 +1,57 @@
+package simcache
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+// TopNByValue returns the n live entries in the cache with the largest values, ordered from largest to smallest.
+// It keeps a heap of at most n entries, so it does not sort the whole cache.
+func TopNByValue[T cmp.Ordered](c *Cache[T], n int) []Entry[T] {
+	if n <= 0 {
+		return nil
+	}
+
+	c.rlock()
+	defer c.mutex.RUnlock()
+
+	h := make(entryHeap[T], 0, min(n, len(c.items)))
+	for k, i := range c.items {
+		if c.expired(i) {
+			continue
+		}
+		if len(h) < n {
+			heap.Push(&h, Entry[T]{Key: k, Value: i.value, Expiration: i.expiration})
+			continue
+		}
+		if cmp.Less(h[0].Value, i.value) {
+			h[0] = Entry[T]{Key: k, Value: i.value, Expiration: i.expiration}
+			heap.Fix(&h, 0)
+		}
+	}
+
+	entries := []Entry[T](h)
+	slices.SortFunc(entries, func(a, b Entry[T]) int {
+		return cmp.Compare(b.Value, a.Value)
+	})
+	return entries
+}
+
+// entryHeap is a min-heap of entries ordered by value.
+type entryHeap[T cmp.Ordered] []Entry[T]
+
+func (h entryHeap[T]) Len() int           { return len(h) }
+func (h entryHeap[T]) Less(i, j int) bool { return cmp.Less(h[i].Value, h[j].Value) }
+func (h entryHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *entryHeap[T]) Push(x any) {
+	*h = append(*h, x.(Entry[T]))
+}
+
+func (h *entryHeap[T]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
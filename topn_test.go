@@ -0,0 +1,56 @@
+package simcache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTopNByValue(t *testing.T) {
+	c := New[int](time.Hour)
+	for i := 0; i < 100; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	c.Set("expired", 1000, time.Nanosecond)
+	time.Sleep(time.Nanosecond * 2)
+
+	type unitTest struct {
+		name     string
+		n        int
+		expected []int
+	}
+
+	tests := []unitTest{
+		{
+			name:     "Zero",
+			n:        0,
+			expected: nil,
+		},
+		{
+			name:     "One",
+			n:        1,
+			expected: []int{99},
+		},
+		{
+			name:     "Five",
+			n:        5,
+			expected: []int{99, 98, 97, 96, 95},
+		},
+	}
+
+	for _, test := range tests {
+		entries := TopNByValue(c, test.n)
+		if len(entries) != len(test.expected) {
+			t.Fatalf("%s FAILED - expected %d but got %d", test.name, len(test.expected), len(entries))
+		}
+		for i, e := range entries {
+			if e.Value != test.expected[i] || e.Key != strconv.Itoa(test.expected[i]) {
+				t.Fatalf("%s FAILED - expected %d but got %d", test.name, test.expected[i], e.Value)
+			}
+		}
+	}
+
+	if entries := TopNByValue(c, 1000); len(entries) != 100 {
+		t.Fatalf("FAILED - expected %d but got %d", 100, len(entries))
+	}
+}
@@ -0,0 +1,30 @@
+package simcache
+
+// View is a read-through view of a Cache that remembers every lookup made through it, so repeated reads of the same
+// key only take the cache's lock once. It is meant to live for a single request.
+// A View is not safe for concurrent use, and does not see writes made to the cache after a key was first read.
+type View[T any] struct {
+	c    *cache[T]
+	seen map[string]viewEntry[T]
+}
+
+type viewEntry[T any] struct {
+	value T
+	found bool
+}
+
+// RequestView returns an empty View of the cache.
+func (c *cache[T]) RequestView() *View[T] {
+	return &View[T]{c: c, seen: make(map[string]viewEntry[T])}
+}
+
+// Get returns the value in the cache for a given key and if it was found, as it was when the key was first read
+// through the view. Misses are remembered as well as hits.
+func (v *View[T]) Get(key string) (T, bool) {
+	if e, found := v.seen[key]; found {
+		return e.value, e.found
+	}
+	value, found := v.c.Get(key)
+	v.seen[key] = viewEntry[T]{value: value, found: found}
+	return value, found
+}
@@ -0,0 +1,46 @@
+package simcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestView_Get(t *testing.T) {
+	c := New[int](time.Hour, WithLockStats[int]())
+	c.Set("one", 1)
+	v := c.RequestView()
+
+	type unitTest struct {
+		name     string
+		key      string
+		expected int
+		found    bool
+		waits    uint64
+	}
+
+	// Each key only takes the cache's lock the first time it is read.
+	tests := []unitTest{
+		{name: "First Hit", key: "one", expected: 1, found: true, waits: 2},
+		{name: "Repeated Hit", key: "one", expected: 1, found: true, waits: 2},
+		{name: "First Miss", key: "two", expected: 0, found: false, waits: 3},
+		{name: "Repeated Miss", key: "two", expected: 0, found: false, waits: 3},
+	}
+
+	for _, test := range tests {
+		value, found := v.Get(test.key)
+		if value != test.expected || found != test.found {
+			t.Fatalf("%s FAILED - expected %d, %t but got %d, %t", test.name, test.expected, test.found, value, found)
+		}
+		if waits := c.Stats().LockWaits; waits != test.waits {
+			t.Fatalf("%s FAILED - expected %d lock waits but got %d", test.name, test.waits, waits)
+		}
+	}
+
+	c.Set("two", 2)
+	if _, found := v.Get("two"); found {
+		t.Fatal(`FAILED - expected the view to keep the miss for "two"`)
+	}
+	if value, found := c.RequestView().Get("two"); !found || value != 2 {
+		t.Fatalf("FAILED - expected %d but got %d, %t", 2, value, found)
+	}
+}